@@ -0,0 +1,89 @@
+package occonfig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/Datadog/opencensus-go-exporter-datadog"
+)
+
+// datadogOptions builds options from datadog://host:port?tracePort=8126&namespace=ns&service=name&tags=k1:v1,k2:v2.
+// Tags can be k1=v1 too because JSON objects are converted to that form.
+// Exporter.Host is the DogStatsD address and the trace agent runs on the same host.
+func datadogOptions(exporter *Exporter, config *Config, logger *log.Logger) (datadog.Options, error) {
+	host, _, err := net.SplitHostPort(exporter.Host)
+	if err != nil {
+		return datadog.Options{}, fmt.Errorf("Failed to parse Datadog host %s: %v", exporter.Host, err)
+	}
+	tracePort := exporter.Options.Get("tracePort")
+	if tracePort == "" {
+		tracePort = "8126"
+	}
+	service := exporter.Options.Get("service")
+	if service == "" {
+		service = config.ServiceName
+	}
+	options := datadog.Options{
+		Namespace: exporter.Options.Get("namespace"),
+		Service:   service,
+		TraceAddr: net.JoinHostPort(host, tracePort),
+		StatsAddr: exporter.Host,
+		OnError: func(err error) {
+			logger.Printf("Failed to export to Datadog: %v", err)
+		},
+	}
+	for _, tags := range exporter.Options["tags"] {
+		for _, tag := range splitExporters(tags) {
+			key, value := tag, ""
+			if i := strings.IndexAny(tag, ":="); i != -1 {
+				key, value = tag[:i], tag[i+1:]
+				tag = key + ":" + value
+			}
+			options.Tags = append(options.Tags, tag)
+			if options.GlobalTags == nil {
+				options.GlobalTags = make(map[string]interface{})
+			}
+			options.GlobalTags[key] = value
+		}
+	}
+	return options, nil
+}
+
+var datadogFactory = &ExporterFactory{
+	Name: "Datadog",
+	Mode: Trace | Stats,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		host, port := hostPort(u, "localhost", "8125")
+		return &Exporter{
+			Type: DATADOG,
+			Host: fmt.Sprintf("%s:%s", host, port),
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		options, err := datadogOptions(exporter, config, logger)
+		if err != nil {
+			return nil, err
+		}
+		dd, err := datadog.NewExporter(options)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create the Datadog exporter: %v", err)
+		}
+		return &ExporterInstance{
+			Trace: dd,
+			Stats: dd,
+			Finalize: func() error {
+				dd.Stop()
+				return nil
+			},
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("datadog", datadogFactory)
+	RegisterExporterFactory("dd", datadogFactory)
+}
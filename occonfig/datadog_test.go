@@ -0,0 +1,137 @@
+package occonfig
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/trace"
+)
+
+func TestDatadogOptions(t *testing.T) {
+	testcases := []struct {
+		Name       string
+		Source     string
+		Namespace  string
+		Service    string
+		TraceAddr  string
+		StatsAddr  string
+		Tags       []string
+		GlobalTags map[string]interface{}
+	}{
+		{
+			Name:      "default",
+			Source:    "datadog",
+			Service:   "my-service",
+			TraceAddr: "localhost:8126",
+			StatsAddr: "localhost:8125",
+		},
+		{
+			Name:      "host and port",
+			Source:    "dd://agent:9125",
+			Service:   "my-service",
+			TraceAddr: "agent:8126",
+			StatsAddr: "agent:9125",
+		},
+		{
+			Name:      "trace port",
+			Source:    "dd://agent?tracePort=9126",
+			Service:   "my-service",
+			TraceAddr: "agent:9126",
+			StatsAddr: "agent:8125",
+		},
+		{
+			Name:       "query",
+			Source:     "datadog://agent?namespace=ns&service=other&tags=env:prod,team:a",
+			Namespace:  "ns",
+			Service:    "other",
+			TraceAddr:  "agent:8126",
+			StatsAddr:  "agent:8125",
+			Tags:       []string{"env:prod", "team:a"},
+			GlobalTags: map[string]interface{}{"env": "prod", "team": "a"},
+		},
+		{
+			Name:       "tags with =",
+			Source:     "datadog://agent?tags=env=prod,team:a",
+			Service:    "my-service",
+			TraceAddr:  "agent:8126",
+			StatsAddr:  "agent:8125",
+			Tags:       []string{"env:prod", "team:a"},
+			GlobalTags: map[string]interface{}{"env": "prod", "team": "a"},
+		},
+		{
+			Name:      "query without host",
+			Source:    "dd?service=other",
+			Service:   "other",
+			TraceAddr: "localhost:8126",
+			StatsAddr: "localhost:8125",
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			exporter, err := SelectTraceExporter(testcase.Source)
+			assert.Nil(t, err)
+			options, err := datadogOptions(exporter, &Config{ServiceName: "my-service"}, testLogger)
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.Namespace, options.Namespace)
+			assert.Equal(t, testcase.Service, options.Service)
+			assert.Equal(t, testcase.TraceAddr, options.TraceAddr)
+			assert.Equal(t, testcase.StatsAddr, options.StatsAddr)
+			assert.Equal(t, testcase.Tags, options.Tags)
+			assert.Equal(t, testcase.GlobalTags, options.GlobalTags)
+		})
+	}
+}
+
+func TestDatadogJSONTags(t *testing.T) {
+	config, err := parseJSON([]byte(`{"trace": {"exporter": "datadog", "datadog": {"tags": {"env": "prod", "team": "a"}}}}`))
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	config.ServiceName = "my-service"
+	exporter, err := SelectTraceExporter(config.TraceExporters[0])
+	assert.Nil(t, err)
+	applyExporterOptions(exporter, config)
+	options, err := datadogOptions(exporter, config, testLogger)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"env:prod", "team:a"}, options.Tags)
+	assert.Equal(t, map[string]interface{}{"env": "prod", "team": "a"}, options.GlobalTags)
+}
+
+func TestDatadogExporter(t *testing.T) {
+	var lock sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		paths = append(paths, r.URL.Path)
+		lock.Unlock()
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	host, port, _ := net.SplitHostPort(u.Host)
+
+	exporter, err := SelectTraceExporter("datadog://" + host + "?tracePort=" + port)
+	assert.Nil(t, err)
+	instance, err := datadogFactory.New(context.Background(), exporter, &Config{ServiceName: "my-service"}, testLogger)
+	assert.Nil(t, err)
+	instance.Trace.ExportSpan(&trace.SpanData{
+		SpanContext: trace.SpanContext{TraceOptions: 1},
+		Name:        "span",
+		StartTime:   time.Now(),
+		EndTime:     time.Now(),
+	})
+	assert.Nil(t, instance.Finalize())
+
+	lock.Lock()
+	defer lock.Unlock()
+	if assert.Equal(t, 1, len(paths)) {
+		assert.Equal(t, "/v0.4/traces", paths[0])
+	}
+}
@@ -1,6 +1,8 @@
 package occonfig
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -13,11 +15,27 @@ func envArrayToMap(envs []string) map[string]string {
 	return result
 }
 
+// parseHeaders parses comma separated headers like "Authorization=Bearer xxx,X-Tenant=team-a".
+func parseHeaders(s string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, header := range splitExporters(s) {
+		i := strings.Index(header, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("Invalid header %q. It should be key=value", header)
+		}
+		result[strings.TrimSpace(header[:i])] = strings.TrimSpace(header[i+1:])
+	}
+	return result, nil
+}
+
 func initByEnvMap(envs []string) (*Config, error) {
+	return initByEnv(envArrayToMap(envs))
+}
+
+func initByEnv(envMaps map[string]string) (*Config, error) {
 	result := &Config{
 		TraceSampler: -1.0,
 	}
-	envMaps := envArrayToMap(envs)
 	if serviceName, ok := envMaps["OC_SERVICE_NAME"]; ok {
 		result.ServiceName = serviceName
 	}
@@ -27,14 +45,52 @@ func initByEnvMap(envs []string) (*Config, error) {
 	if zpage, ok := envMaps["OC_ZPAGE"]; ok {
 		result.ZPage = zpage
 	}
+	if listenPolicy, ok := envMaps["OC_LISTEN_POLICY"]; ok {
+		result.ListenPolicy = listenPolicy
+	}
+	if caCert, ok := envMaps["OC_TLS_CA_CERT"]; ok {
+		result.TLSCACert = caCert
+	}
+	if clientCert, ok := envMaps["OC_TLS_CLIENT_CERT"]; ok {
+		result.TLSClientCert = clientCert
+	}
+	if clientKey, ok := envMaps["OC_TLS_CLIENT_KEY"]; ok {
+		result.TLSClientKey = clientKey
+	}
+	if insecureSkipVerify, ok := envMaps["OC_TLS_INSECURE_SKIP_VERIFY"]; ok && insecureSkipVerify != "" {
+		value, err := strconv.ParseBool(insecureSkipVerify)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value OC_TLS_INSECURE_SKIP_VERIFY %q. It should be 'true'|'false'", insecureSkipVerify)
+		}
+		result.TLSInsecureSkipVerify = &value
+	}
+	if headers, ok := envMaps["OC_EXPORTER_HEADERS"]; ok {
+		parsedHeaders, err := parseHeaders(headers)
+		if err != nil {
+			return nil, err
+		}
+		result.ExporterHeaders = parsedHeaders
+	}
+	if proxy, ok := envMaps["OC_EXPORTER_PROXY"]; ok {
+		result.ExporterProxy = proxy
+	}
 	if configJson, ok := envMaps["OC_CONFIG_JSON"]; ok {
 		result.ConfigFile = configJson
 	}
 	if honeycombKey, ok := envMaps["OC_HONEYCOMB_WRITE_KEY"]; ok {
 		result.HoneycombKey = honeycombKey
 	}
+	if honeycombDataset, ok := envMaps["OC_HONEYCOMB_DATASET"]; ok {
+		result.HoneycombDataset = honeycombDataset
+	}
+	if honeycombAPIHost, ok := envMaps["OC_HONEYCOMB_API_HOST"]; ok {
+		result.HoneycombAPIHost = honeycombAPIHost
+	}
 	if tracer, ok := envMaps["OC_TRACE_EXPORTER"]; ok {
-		result.TraceExporter = tracer
+		result.TraceExporters = splitExporterURLs(tracer)
+	}
+	if idGenerator, ok := envMaps["OC_TRACE_ID_GENERATOR"]; ok {
+		result.TraceIDGenerator = idGenerator
 	}
 	s, err := SelectSampler(envMaps["OC_TRACE_SAMPLER"])
 	if err != nil {
@@ -43,7 +99,7 @@ func initByEnvMap(envs []string) (*Config, error) {
 		result.TraceSampler = s
 	}
 	if tracer, ok := envMaps["OC_STATS_EXPORTER"]; ok {
-		result.StatsExporter = tracer
+		result.StatsExporters = splitExporterURLs(tracer)
 	}
 	return result, nil
 }
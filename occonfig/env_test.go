@@ -8,16 +8,26 @@ import (
 
 func TestEnv(t *testing.T) {
 	testcases := []struct {
-		Name          string
-		Envs          []string
-		ServiceName   string
-		ServiceUrl    string
-		ZPage         string
-		ConfigFile    string
-		HoneycombKey  string
-		TraceExporter string
-		TraceSampler  float64
-		StatsExporter string
+		Name                  string
+		Envs                  []string
+		ServiceName           string
+		ServiceUrl            string
+		ZPage                 string
+		ListenPolicy          string
+		TLSCACert             string
+		TLSClientCert         string
+		TLSClientKey          string
+		TLSInsecureSkipVerify *bool
+		ExporterHeaders       map[string]string
+		ExporterProxy         string
+		ConfigFile            string
+		HoneycombKey          string
+		HoneycombDataset      string
+		HoneycombAPIHost      string
+		TraceExporters        []string
+		TraceSampler          float64
+		TraceIDGenerator      string
+		StatsExporters        []string
 	}{
 		{
 			Name:         "service-name test",
@@ -37,6 +47,28 @@ func TestEnv(t *testing.T) {
 			ZPage:        "http://:8888/debug",
 			TraceSampler: -1,
 		},
+		{
+			Name:         "listen-policy test",
+			Envs:         []string{"OC_LISTEN_POLICY=warn", "HOME=test"},
+			ListenPolicy: "warn",
+			TraceSampler: -1,
+		},
+		{
+			Name:                  "tls test",
+			Envs:                  []string{"OC_TLS_CA_CERT=ca.crt", "OC_TLS_CLIENT_CERT=client.crt", "OC_TLS_CLIENT_KEY=client.key", "OC_TLS_INSECURE_SKIP_VERIFY=true", "HOME=test"},
+			TLSCACert:             "ca.crt",
+			TLSClientCert:         "client.crt",
+			TLSClientKey:          "client.key",
+			TLSInsecureSkipVerify: boolPtr(true),
+			TraceSampler:          -1,
+		},
+		{
+			Name:            "exporter-headers test",
+			Envs:            []string{"OC_EXPORTER_HEADERS=Authorization=Bearer token, X-Tenant=team-a", "OC_EXPORTER_PROXY=http://proxy:8080", "HOME=test"},
+			ExporterHeaders: map[string]string{"Authorization": "Bearer token", "X-Tenant": "team-a"},
+			ExporterProxy:   "http://proxy:8080",
+			TraceSampler:    -1,
+		},
 		{
 			Name:         "config-json test",
 			Envs:         []string{"OC_CONFIG_JSON=config.json", "HOME=test"},
@@ -50,10 +82,29 @@ func TestEnv(t *testing.T) {
 			TraceSampler: -1,
 		},
 		{
-			Name:          "trace-exporter test",
-			Envs:          []string{"OC_TRACE_EXPORTER=jaeger://localhost:6831", "HOME=test"},
-			TraceExporter: "jaeger://localhost:6831",
-			TraceSampler:  -1,
+			Name:             "honeycomb-dataset test",
+			Envs:             []string{"OC_HONEYCOMB_DATASET=my-dataset", "OC_HONEYCOMB_API_HOST=http://localhost:8080", "HOME=test"},
+			HoneycombDataset: "my-dataset",
+			HoneycombAPIHost: "http://localhost:8080",
+			TraceSampler:     -1,
+		},
+		{
+			Name:           "trace-exporter test",
+			Envs:           []string{"OC_TRACE_EXPORTER=jaeger://localhost:6831", "HOME=test"},
+			TraceExporters: []string{"jaeger://localhost:6831"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "trace-exporter test (multiple)",
+			Envs:           []string{"OC_TRACE_EXPORTER=jaeger://localhost:6831, zap", "HOME=test"},
+			TraceExporters: []string{"jaeger://localhost:6831", "zap"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:             "trace-id-generator test",
+			Envs:             []string{"OC_TRACE_ID_GENERATOR=xray", "HOME=test"},
+			TraceIDGenerator: "xray",
+			TraceSampler:     -1,
 		},
 		{
 			Name:         "trace-sampler test (1)",
@@ -71,10 +122,28 @@ func TestEnv(t *testing.T) {
 			TraceSampler: 0.25,
 		},
 		{
-			Name:          "stats-exporter test",
-			Envs:          []string{"OC_STATS_EXPORTER=prometheus://:8888", "HOME=test"},
-			StatsExporter: "prometheus://:8888",
-			TraceSampler:  -1,
+			Name:           "stats-exporter test",
+			Envs:           []string{"OC_STATS_EXPORTER=prometheus://:8888", "HOME=test"},
+			StatsExporters: []string{"prometheus://:8888"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "stats-exporter test (multiple)",
+			Envs:           []string{"OC_STATS_EXPORTER=prometheus://:8888,sd://my-project", "HOME=test"},
+			StatsExporters: []string{"prometheus://:8888", "sd://my-project"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "stats-exporter test (commas in query)",
+			Envs:           []string{"OC_STATS_EXPORTER=prometheus://127.0.0.1:8888/custom-metrics?constLabels=env=prod,team=a,graphite://localhost:2003?tags=env=prod,team=a", "HOME=test"},
+			StatsExporters: []string{"prometheus://127.0.0.1:8888/custom-metrics?constLabels=env=prod,team=a", "graphite://localhost:2003?tags=env=prod,team=a"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "trace-exporter test (commas in query)",
+			Envs:           []string{"OC_TRACE_EXPORTER=jaeger://localhost?processTags=env=prod,team=a,datadog://localhost?tags=env:prod,team:a,zap", "HOME=test"},
+			TraceExporters: []string{"jaeger://localhost?processTags=env=prod,team=a", "datadog://localhost?tags=env:prod,team:a", "zap"},
+			TraceSampler:   -1,
 		},
 	}
 	for _, testcase := range testcases {
@@ -87,11 +156,50 @@ func TestEnv(t *testing.T) {
 			assert.Equal(t, testcase.ServiceName, result.ServiceName)
 			assert.Equal(t, testcase.ServiceUrl, result.ServiceUrl)
 			assert.Equal(t, testcase.ZPage, result.ZPage)
+			assert.Equal(t, testcase.ListenPolicy, result.ListenPolicy)
+			assert.Equal(t, testcase.TLSCACert, result.TLSCACert)
+			assert.Equal(t, testcase.TLSClientCert, result.TLSClientCert)
+			assert.Equal(t, testcase.TLSClientKey, result.TLSClientKey)
+			assert.Equal(t, testcase.TLSInsecureSkipVerify, result.TLSInsecureSkipVerify)
+			assert.Equal(t, testcase.ExporterHeaders, result.ExporterHeaders)
+			assert.Equal(t, testcase.ExporterProxy, result.ExporterProxy)
 			assert.Equal(t, testcase.ConfigFile, result.ConfigFile)
 			assert.Equal(t, testcase.HoneycombKey, result.HoneycombKey)
-			assert.Equal(t, testcase.TraceExporter, result.TraceExporter)
-			assert.Equal(t, testcase.StatsExporter, result.StatsExporter)
+			assert.Equal(t, testcase.HoneycombDataset, result.HoneycombDataset)
+			assert.Equal(t, testcase.HoneycombAPIHost, result.HoneycombAPIHost)
+			assert.Equal(t, testcase.TraceExporters, result.TraceExporters)
+			assert.Equal(t, testcase.TraceIDGenerator, result.TraceIDGenerator)
+			assert.Equal(t, testcase.StatsExporters, result.StatsExporters)
 			assert.InDelta(t, testcase.TraceSampler, result.TraceSampler, 0.01)
 		})
 	}
 }
+
+func TestEnvExporterURLsWithCommas(t *testing.T) {
+	// URLs in README. Their queries have commas
+	traceExporters := "jaeger://localhost?processTags=env=prod,team=a&bufferMaxCount=1000," +
+		"datadog://localhost?tracePort=8126&namespace=ns&service=my-service&tags=env:prod,team:a," +
+		"stackdriver://my-project?labels=env=prod,team=a"
+	statsExporters := "prometheus://127.0.0.1:8888/custom-metrics?constLabels=env=prod,team=a," +
+		"graphite://localhost:2003?namespace=ns&tags=env=prod,team=a&interval=10s," +
+		"pushgateway://localhost:9091/my-job?grouping=instance=host1,env=prod"
+	result, err := initByEnvMap([]string{"OC_TRACE_EXPORTER=" + traceExporters, "OC_STATS_EXPORTER=" + statsExporters})
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	assert.Len(t, result.TraceExporters, 3)
+	for _, source := range result.TraceExporters {
+		_, err := SelectTraceExporter(source)
+		assert.Nil(t, err, source)
+	}
+	assert.Len(t, result.StatsExporters, 3)
+	for _, source := range result.StatsExporters {
+		_, err := SelectStatsExporter(source)
+		assert.Nil(t, err, source)
+	}
+	exporter, err := SelectStatsExporter(result.StatsExporters[0])
+	if assert.Nil(t, err) {
+		assert.Equal(t, "env=prod,team=a", exporter.Options.Get("constLabels"))
+	}
+}
@@ -2,17 +2,55 @@ package occonfig
 
 import (
 	"flag"
+	"strconv"
 )
 
+// optionalBoolValue is a bool flag that keeps nil if it is not specified.
+// It is used for settings that a higher priority layer can turn off (e.g. -oc-tls-insecure-skip-verify=false).
+type optionalBoolValue struct {
+	target **bool
+}
+
+func (v *optionalBoolValue) Set(s string) error {
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v.target = &value
+	return nil
+}
+
+func (v *optionalBoolValue) String() string {
+	if v == nil || v.target == nil || *v.target == nil {
+		return ""
+	}
+	return strconv.FormatBool(**v.target)
+}
+
+func (v *optionalBoolValue) IsBoolFlag() bool {
+	return true
+}
+
 type FlagResult struct {
-	ServiceName   string
-	ServiceUrl    string
-	HoneycombKey  string
-	ConfigFile    string
-	TraceExporter string
-	TraceSampler  string
-	StatsExporter string
-	ZPage         string
+	ServiceName      string
+	ServiceUrl       string
+	HoneycombKey     string
+	HoneycombDataset string
+	HoneycombAPIHost string
+	ConfigFile       string
+	TraceExporter    string
+	TraceSampler     string
+	TraceIDGenerator string
+	StatsExporter    string
+	ZPage            string
+	ListenPolicy     string
+
+	TLSCACert             string
+	TLSClientCert         string
+	TLSClientKey          string
+	TLSInsecureSkipVerify *bool
+	ExporterHeaders       string
+	ExporterProxy         string
 }
 
 var defaultFlagResult *FlagResult
@@ -39,23 +77,53 @@ func InitFlagSet(flagset *flag.FlagSet, mode Mode) *FlagResult {
 	flagset.StringVar(
 		&result.ZPage, "oc-zpage", "",
 		"ZPage in-process debug console url (e.g. http://:8888/debug")
+	flagset.StringVar(
+		&result.ListenPolicy, "oc-listen-policy", "",
+		"Policy when the port of Prometheus or ZPage is not available ('fail'(default), 'warn', 'free-port')")
+	flagset.StringVar(
+		&result.TLSCACert, "oc-tls-ca-cert", "",
+		"CA certificate file path to verify HTTPS exporters")
+	flagset.StringVar(
+		&result.TLSClientCert, "oc-tls-client-cert", "",
+		"Client certificate file path for HTTPS exporters (mTLS)")
+	flagset.StringVar(
+		&result.TLSClientKey, "oc-tls-client-key", "",
+		"Client key file path for HTTPS exporters (mTLS)")
+	flagset.Var(
+		&optionalBoolValue{&result.TLSInsecureSkipVerify}, "oc-tls-insecure-skip-verify",
+		"Skip verifying server certificates of HTTPS exporters (for development)")
+	flagset.StringVar(
+		&result.ExporterHeaders, "oc-exporter-headers", "",
+		"Comma separated headers for HTTP exporters (e.g. Authorization=file://token.txt,X-Tenant=team-a)")
+	flagset.StringVar(
+		&result.ExporterProxy, "oc-exporter-proxy", "",
+		"Proxy URL for HTTP exporters")
 
 	if mode&Trace == Trace {
 		flagset.StringVar(
 			&result.TraceExporter, "oc-trace-exporter", "",
-			"OpenCensus trace setting (e.g. stackdriver://demo-project-id, jaeger://localhost:6831")
+			"OpenCensus trace setting. Comma separated list is acceptable (e.g. stackdriver://demo-project-id, jaeger://localhost:6831,zap")
 		flagset.StringVar(
 			&result.TraceSampler, "oc-trace-sampler", "",
 			"Trace sampling rate ('always', 'never', '0-1'")
+		flagset.StringVar(
+			&result.TraceIDGenerator, "oc-trace-id-generator", "",
+			"Trace ID generator ('random', 'xray'). Default is 'xray' if trace exporter is X-Ray, otherwise 'random'")
 		flagset.StringVar(
 			&result.HoneycombKey, "oc-honeycomb-write-key", "",
 			"Honeycomb.io write key or file path(file://) (it is needed when trace exporter is honeycomb)")
+		flagset.StringVar(
+			&result.HoneycombDataset, "oc-honeycomb-dataset", "",
+			"Honeycomb.io dataset name (default is service name)")
+		flagset.StringVar(
+			&result.HoneycombAPIHost, "oc-honeycomb-api-host", "",
+			"Honeycomb.io API host (default is https://api.honeycomb.io/)")
 	}
 
 	if mode&Stats == Stats {
 		flagset.StringVar(
 			&result.StatsExporter, "oc-stats-exporter", "",
-			"OpenCensus stats setting (e.g. stackdriver://demo-project-id, prometheus://localhost:8888")
+			"OpenCensus stats setting. Comma separated list is acceptable (e.g. stackdriver://demo-project-id, prometheus://:8888,graphite")
 	}
 
 	getConfigFromCommandLine = func() (*Config, error) {
@@ -67,13 +135,28 @@ func InitFlagSet(flagset *flag.FlagSet, mode Mode) *FlagResult {
 
 func parseFlagResult(flagResult *FlagResult) (config *Config, err error) {
 	config = &Config{
-		ServiceName:   flagResult.ServiceName,
-		ServiceUrl:    flagResult.ServiceUrl,
-		ConfigFile:    flagResult.ConfigFile,
-		ZPage:         flagResult.ZPage,
-		TraceExporter: flagResult.TraceExporter,
-		HoneycombKey:  flagResult.HoneycombKey,
-		StatsExporter: flagResult.StatsExporter,
+		ServiceName:           flagResult.ServiceName,
+		ServiceUrl:            flagResult.ServiceUrl,
+		ConfigFile:            flagResult.ConfigFile,
+		ZPage:                 flagResult.ZPage,
+		ListenPolicy:          flagResult.ListenPolicy,
+		TLSCACert:             flagResult.TLSCACert,
+		TLSClientCert:         flagResult.TLSClientCert,
+		TLSClientKey:          flagResult.TLSClientKey,
+		TLSInsecureSkipVerify: flagResult.TLSInsecureSkipVerify,
+		ExporterProxy:         flagResult.ExporterProxy,
+		TraceExporters:        splitExporterURLs(flagResult.TraceExporter),
+		TraceIDGenerator:      flagResult.TraceIDGenerator,
+		HoneycombKey:          flagResult.HoneycombKey,
+		HoneycombDataset:      flagResult.HoneycombDataset,
+		HoneycombAPIHost:      flagResult.HoneycombAPIHost,
+		StatsExporters:        splitExporterURLs(flagResult.StatsExporter),
+	}
+	if flagResult.ExporterHeaders != "" {
+		config.ExporterHeaders, err = parseHeaders(flagResult.ExporterHeaders)
+		if err != nil {
+			return nil, err
+		}
 	}
 	s, e := SelectSampler(flagResult.TraceSampler)
 	if e != nil {
@@ -9,16 +9,26 @@ import (
 
 func TestInitFlagSet(t *testing.T) {
 	testcases := []struct {
-		Name          string
-		Params        []string
-		ServiceName   string
-		ServiceUrl    string
-		ConfigFile    string
-		HoneycombKey  string
-		TraceExporter string
-		TraceSampler  float64
-		StatsExporter string
-		ZPage         string
+		Name                  string
+		Params                []string
+		ServiceName           string
+		ServiceUrl            string
+		ConfigFile            string
+		HoneycombKey          string
+		HoneycombDataset      string
+		HoneycombAPIHost      string
+		TraceExporters        []string
+		TraceSampler          float64
+		TraceIDGenerator      string
+		StatsExporters        []string
+		ZPage                 string
+		ListenPolicy          string
+		TLSCACert             string
+		TLSClientCert         string
+		TLSClientKey          string
+		TLSInsecureSkipVerify *bool
+		ExporterHeaders       map[string]string
+		ExporterProxy         string
 	}{
 		{
 			Name:         "service-name test",
@@ -50,6 +60,34 @@ func TestInitFlagSet(t *testing.T) {
 			ZPage:        "http://:8888/debug",
 			TraceSampler: -1,
 		},
+		{
+			Name:         "listen-policy test",
+			Params:       []string{"-oc-listen-policy", "warn", "etc", "etc"},
+			ListenPolicy: "warn",
+			TraceSampler: -1,
+		},
+		{
+			Name:                  "tls test",
+			Params:                []string{"-oc-tls-ca-cert", "ca.crt", "-oc-tls-client-cert", "client.crt", "-oc-tls-client-key", "client.key", "-oc-tls-insecure-skip-verify", "etc"},
+			TLSCACert:             "ca.crt",
+			TLSClientCert:         "client.crt",
+			TLSClientKey:          "client.key",
+			TLSInsecureSkipVerify: boolPtr(true),
+			TraceSampler:          -1,
+		},
+		{
+			Name:                  "tls test (insecure-skip-verify=false)",
+			Params:                []string{"-oc-tls-insecure-skip-verify=false", "etc"},
+			TLSInsecureSkipVerify: boolPtr(false),
+			TraceSampler:          -1,
+		},
+		{
+			Name:            "exporter-headers test",
+			Params:          []string{"-oc-exporter-headers", "Authorization=Bearer token,X-Tenant=team-a", "-oc-exporter-proxy", "http://proxy:8080", "etc"},
+			ExporterHeaders: map[string]string{"Authorization": "Bearer token", "X-Tenant": "team-a"},
+			ExporterProxy:   "http://proxy:8080",
+			TraceSampler:    -1,
+		},
 		{
 			Name:         "honeycomb-write-key test",
 			Params:       []string{"-oc-honeycomb-write-key", "honeycomb.key", "etc", "etc"},
@@ -57,10 +95,29 @@ func TestInitFlagSet(t *testing.T) {
 			TraceSampler: -1,
 		},
 		{
-			Name:          "trace-exporter test",
-			Params:        []string{"-oc-trace-exporter", "jaeger://localhost:6831", "etc", "etc"},
-			TraceExporter: "jaeger://localhost:6831",
-			TraceSampler:  -1,
+			Name:             "honeycomb-dataset test",
+			Params:           []string{"-oc-honeycomb-dataset", "my-dataset", "-oc-honeycomb-api-host", "http://localhost:8080", "etc"},
+			HoneycombDataset: "my-dataset",
+			HoneycombAPIHost: "http://localhost:8080",
+			TraceSampler:     -1,
+		},
+		{
+			Name:           "trace-exporter test",
+			Params:         []string{"-oc-trace-exporter", "jaeger://localhost:6831", "etc", "etc"},
+			TraceExporters: []string{"jaeger://localhost:6831"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "trace-exporter test (multiple)",
+			Params:         []string{"-oc-trace-exporter", "jaeger://localhost:6831,zap", "etc", "etc"},
+			TraceExporters: []string{"jaeger://localhost:6831", "zap"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:             "trace-id-generator test",
+			Params:           []string{"-oc-trace-id-generator", "xray", "etc"},
+			TraceIDGenerator: "xray",
+			TraceSampler:     -1,
 		},
 		{
 			Name:         "trace-sampler test (1)",
@@ -78,10 +135,22 @@ func TestInitFlagSet(t *testing.T) {
 			TraceSampler: 0.25,
 		},
 		{
-			Name:          "stats-exporter test",
-			Params:        []string{"-oc-stats-exporter", "prometheus://localhost:8888", "etc", "etc"},
-			StatsExporter: "prometheus://localhost:8888",
-			TraceSampler:  -1,
+			Name:           "stats-exporter test",
+			Params:         []string{"-oc-stats-exporter", "prometheus://localhost:8888", "etc", "etc"},
+			StatsExporters: []string{"prometheus://localhost:8888"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "stats-exporter test (multiple)",
+			Params:         []string{"-oc-stats-exporter", "prometheus://localhost:8888,sd://my-project", "etc", "etc"},
+			StatsExporters: []string{"prometheus://localhost:8888", "sd://my-project"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "stats-exporter test (commas in query)",
+			Params:         []string{"-oc-stats-exporter", "stackdriver://my-project?labels=env=prod,team=a&resourceLabels=zone=asia-northeast1-a,pushgateway://localhost:9091/my-job?grouping=instance=host1,env=prod", "etc"},
+			StatsExporters: []string{"stackdriver://my-project?labels=env=prod,team=a&resourceLabels=zone=asia-northeast1-a", "pushgateway://localhost:9091/my-job?grouping=instance=host1,env=prod"},
+			TraceSampler:   -1,
 		},
 	}
 	for _, testcase := range testcases {
@@ -98,10 +167,20 @@ func TestInitFlagSet(t *testing.T) {
 			assert.Equal(t, testcase.ServiceUrl, result.ServiceUrl)
 			assert.Equal(t, testcase.ConfigFile, result.ConfigFile)
 			assert.Equal(t, testcase.ZPage, result.ZPage)
+			assert.Equal(t, testcase.ListenPolicy, result.ListenPolicy)
+			assert.Equal(t, testcase.TLSCACert, result.TLSCACert)
+			assert.Equal(t, testcase.TLSClientCert, result.TLSClientCert)
+			assert.Equal(t, testcase.TLSClientKey, result.TLSClientKey)
+			assert.Equal(t, testcase.TLSInsecureSkipVerify, result.TLSInsecureSkipVerify)
+			assert.Equal(t, testcase.ExporterHeaders, result.ExporterHeaders)
+			assert.Equal(t, testcase.ExporterProxy, result.ExporterProxy)
 			assert.Equal(t, testcase.HoneycombKey, result.HoneycombKey)
-			assert.Equal(t, testcase.TraceExporter, result.TraceExporter)
+			assert.Equal(t, testcase.HoneycombDataset, result.HoneycombDataset)
+			assert.Equal(t, testcase.HoneycombAPIHost, result.HoneycombAPIHost)
+			assert.Equal(t, testcase.TraceExporters, result.TraceExporters)
+			assert.Equal(t, testcase.TraceIDGenerator, result.TraceIDGenerator)
 			assert.InDelta(t, testcase.TraceSampler, result.TraceSampler, 0.01)
-			assert.Equal(t, testcase.StatsExporter, result.StatsExporter)
+			assert.Equal(t, testcase.StatsExporters, result.StatsExporters)
 		})
 	}
 }
@@ -0,0 +1,173 @@
+package occonfig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"contrib.go.opencensus.io/exporter/graphite"
+	"go.opencensus.io/stats/view"
+)
+
+// graphiteOptions builds options from graphite://host:port?namespace=ns&tags=k1=v1,k2=v2.
+// namespace defaults to the service name.
+func graphiteOptions(exporter *Exporter, config *Config, logger *log.Logger) (graphite.Options, error) {
+	host, rawPort, err := net.SplitHostPort(exporter.Host)
+	if err != nil {
+		return graphite.Options{}, fmt.Errorf("Failed to parse Graphite host %s: %v", exporter.Host, err)
+	}
+	port, err := strconv.Atoi(rawPort)
+	if err != nil {
+		return graphite.Options{}, fmt.Errorf("Failed to parse Graphite port %s: %v", rawPort, err)
+	}
+	// The Graphite exporter always joins tags with ';' (Graphite 1.1 tag format)
+	if separator := exporter.Options.Get("tagSeparator"); separator != "" && separator != ";" {
+		return graphite.Options{}, fmt.Errorf("Graphite exporter doesn't support tag separator %q. Only ';' is available", separator)
+	}
+	namespace := config.ServiceName
+	if _, ok := exporter.Options["namespace"]; ok {
+		namespace = exporter.Options.Get("namespace")
+	}
+	options := graphite.Options{
+		Host:      host,
+		Port:      port,
+		Namespace: namespace,
+		// it must not be nil because the Graphite exporter calls it directly while sending metrics
+		OnError: func(err error) {
+			logger.Printf("Failed to export to Graphite: %v", err)
+		},
+	}
+	for _, tags := range exporter.Options["tags"] {
+		options.Tags = append(options.Tags, splitExporters(tags)...)
+	}
+	return options, nil
+}
+
+// graphiteInterval returns the reporting interval in interval query (e.g. 10s). 0 means every view data is sent.
+func graphiteInterval(exporter *Exporter) (time.Duration, error) {
+	rawInterval := exporter.Options.Get("interval")
+	if rawInterval == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(rawInterval)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("Invalid Graphite reporting interval %q. It should be a positive duration like 10s", rawInterval)
+	}
+	return interval, nil
+}
+
+// intervalExporter keeps the latest data of each view and passes them to the exporter at the interval.
+type intervalExporter struct {
+	lock     sync.Mutex
+	exporter view.Exporter
+	latest   map[string]*view.Data
+	order    []string
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newIntervalExporter(exporter view.Exporter, interval time.Duration) *intervalExporter {
+	e := &intervalExporter{
+		exporter: exporter,
+		latest:   make(map[string]*view.Data),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		defer close(e.done)
+		for {
+			select {
+			case <-ticker.C:
+				e.flush()
+			case <-e.stop:
+				return
+			}
+		}
+	}()
+	return e
+}
+
+func (e *intervalExporter) ExportView(vd *view.Data) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if _, ok := e.latest[vd.View.Name]; !ok {
+		e.order = append(e.order, vd.View.Name)
+	}
+	e.latest[vd.View.Name] = vd
+}
+
+func (e *intervalExporter) flush() {
+	e.lock.Lock()
+	var data []*view.Data
+	for _, name := range e.order {
+		data = append(data, e.latest[name])
+	}
+	e.latest = make(map[string]*view.Data)
+	e.order = nil
+	e.lock.Unlock()
+	for _, vd := range data {
+		e.exporter.ExportView(vd)
+	}
+}
+
+// Close stops the timer and passes the remaining data.
+func (e *intervalExporter) Close() {
+	close(e.stop)
+	<-e.done
+	e.flush()
+}
+
+var graphiteFactory = &ExporterFactory{
+	Name: "Graphite",
+	Mode: Stats,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		host, port := hostPort(u, "localhost", "2003")
+		return &Exporter{
+			Type: GRAPHITE,
+			Host: fmt.Sprintf("%s:%s", host, port),
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		options, err := graphiteOptions(exporter, config, logger)
+		if err != nil {
+			return nil, err
+		}
+		interval, err := graphiteInterval(exporter)
+		if err != nil {
+			return nil, err
+		}
+		ge, err := graphite.NewExporter(options)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create Graphite exporter: %v", err)
+		}
+		if interval == 0 {
+			return &ExporterInstance{
+				Stats: ge,
+				Finalize: func() error {
+					ge.Flush()
+					return nil
+				},
+			}, nil
+		}
+		ie := newIntervalExporter(ge, interval)
+		return &ExporterInstance{
+			Stats: ie,
+			Finalize: func() error {
+				ie.Close()
+				ge.Flush()
+				return nil
+			},
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("graphite", graphiteFactory)
+}
@@ -0,0 +1,163 @@
+package occonfig
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+func TestGraphiteOptions(t *testing.T) {
+	testcases := []struct {
+		Name      string
+		Source    string
+		Options   map[string]url.Values
+		Host      string
+		Port      int
+		Namespace string
+		Tags      []string
+		Interval  time.Duration
+		Error     bool
+	}{
+		{
+			Name:      "default",
+			Source:    "graphite",
+			Host:      "localhost",
+			Port:      2003,
+			Namespace: "my-service",
+		},
+		{
+			Name:      "host and port",
+			Source:    "graphite://carbon:2103",
+			Host:      "carbon",
+			Port:      2103,
+			Namespace: "my-service",
+		},
+		{
+			Name:      "query",
+			Source:    "graphite://carbon?namespace=ns&tags=env=prod,team=a&tagSeparator=;&interval=10s",
+			Host:      "carbon",
+			Port:      2003,
+			Namespace: "ns",
+			Tags:      []string{"env=prod", "team=a"},
+			Interval:  10 * time.Second,
+		},
+		{
+			Name:      "empty namespace",
+			Source:    "graphite://carbon?namespace=",
+			Host:      "carbon",
+			Port:      2003,
+			Namespace: "",
+		},
+		{
+			Name:      "JSON options",
+			Source:    "graphite://carbon?namespace=ns",
+			Options:   map[string]url.Values{"graphite": {"namespace": {"json"}, "interval": {"1m"}}},
+			Host:      "carbon",
+			Port:      2003,
+			Namespace: "ns",
+			Interval:  time.Minute,
+		},
+		{
+			Name:   "invalid tag separator",
+			Source: "graphite://carbon?tagSeparator=.",
+			Error:  true,
+		},
+		{
+			Name:   "invalid interval",
+			Source: "graphite://carbon?interval=10",
+			Error:  true,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			exporter, err := SelectStatsExporter(testcase.Source)
+			assert.Nil(t, err)
+			config := &Config{ServiceName: "my-service", ExporterOptions: testcase.Options}
+			applyExporterOptions(exporter, config)
+			options, err := graphiteOptions(exporter, config, testLogger)
+			if err == nil {
+				var interval time.Duration
+				interval, err = graphiteInterval(exporter)
+				assert.Equal(t, testcase.Interval, interval)
+			}
+			if testcase.Error {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.Host, options.Host)
+			assert.Equal(t, testcase.Port, options.Port)
+			assert.Equal(t, testcase.Namespace, options.Namespace)
+			assert.Equal(t, testcase.Tags, options.Tags)
+		})
+	}
+}
+
+type recordingViewExporter struct {
+	names []string
+}
+
+func (r *recordingViewExporter) ExportView(vd *view.Data) {
+	r.names = append(r.names, vd.View.Name)
+}
+
+func TestIntervalExporter(t *testing.T) {
+	recorder := &recordingViewExporter{}
+	exporter := newIntervalExporter(recorder, time.Hour)
+	first := &view.View{Name: "first"}
+	second := &view.View{Name: "second"}
+	exporter.ExportView(&view.Data{View: first})
+	exporter.ExportView(&view.Data{View: second})
+	exporter.ExportView(&view.Data{View: first})
+	assert.Equal(t, 0, len(recorder.names))
+	exporter.Close()
+	assert.Equal(t, []string{"first", "second"}, recorder.names)
+}
+
+func TestGraphiteExporter(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+			conn.Close()
+		}
+	}()
+
+	exporter, err := SelectStatsExporter("graphite://" + listener.Addr().String() + "?namespace=ns&tags=env=test")
+	assert.Nil(t, err)
+	instance, err := graphiteFactory.New(context.Background(), exporter, &Config{ServiceName: "my-service"}, testLogger)
+	assert.Nil(t, err)
+	measure := stats.Int64("graphite_test", "test measure", stats.UnitDimensionless)
+	instance.Stats.ExportView(&view.Data{
+		View:  &view.View{Name: "requests", Measure: measure, Aggregation: view.Count()},
+		Start: time.Now(),
+		End:   time.Now(),
+		Rows:  []*view.Row{{Data: &view.CountData{Value: 3}}},
+	})
+	assert.Nil(t, instance.Finalize())
+
+	select {
+	case line := <-lines:
+		assert.True(t, strings.HasPrefix(line, "ns.requests;env=test 3 "), line)
+	case <-time.After(5 * time.Second):
+		t.Error("Graphite didn't receive metrics")
+	}
+}
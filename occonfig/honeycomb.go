@@ -0,0 +1,54 @@
+package occonfig
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+
+	honeycomb "github.com/honeycombio/opencensus-exporter/honeycomb"
+)
+
+// honeycombDataset returns the dataset name in URL (honeycomb://dataset), config or service name.
+func honeycombDataset(exporter *Exporter, config *Config) string {
+	if exporter.Host != "" {
+		return exporter.Host
+	}
+	if config.HoneycombDataset != "" {
+		return config.HoneycombDataset
+	}
+	return config.ServiceName
+}
+
+var honeycombFactory = &ExporterFactory{
+	Name: "Honeycomb",
+	Mode: Trace,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		return &Exporter{
+			Type: HONEYCOMB,
+			Host: u.Host,
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		if config.HoneycombKey == "" {
+			return nil, errors.New("Honeycomb Write Key is empty")
+		}
+		hc := honeycomb.NewExporter(config.HoneycombKey, honeycombDataset(exporter, config))
+		if config.HoneycombAPIHost != "" {
+			hc.Builder.APIHost = config.HoneycombAPIHost
+		}
+		hc.SampleFraction = config.TraceSampler
+		hc.ServiceName = config.ServiceName
+		return &ExporterInstance{
+			Trace: hc,
+			Finalize: func() error {
+				hc.Close()
+				return nil
+			},
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("honeycomb", honeycombFactory)
+}
@@ -0,0 +1,88 @@
+package occonfig
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/trace"
+)
+
+func TestHoneycombExporter(t *testing.T) {
+	var lock sync.Mutex
+	var paths []string
+	var writeKeys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		lock.Lock()
+		paths = append(paths, r.URL.Path)
+		writeKeys = append(writeKeys, r.Header.Get("X-Honeycomb-Team"))
+		lock.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"status":202}]`))
+	}))
+	defer server.Close()
+
+	exporter, err := SelectTraceExporter("honeycomb://my-dataset")
+	assert.Nil(t, err)
+	instance, err := honeycombFactory.New(context.Background(), exporter, &Config{
+		ServiceName:      "my-service",
+		HoneycombKey:     "dummy-key",
+		HoneycombAPIHost: server.URL,
+		TraceSampler:     1.0,
+	}, testLogger)
+	assert.Nil(t, err)
+	instance.Trace.ExportSpan(&trace.SpanData{
+		Name:      "span",
+		StartTime: time.Now(),
+		EndTime:   time.Now(),
+	})
+	assert.Nil(t, instance.Finalize())
+
+	lock.Lock()
+	defer lock.Unlock()
+	if assert.Equal(t, 1, len(paths)) {
+		assert.Equal(t, "/1/batch/my-dataset", paths[0])
+		assert.Equal(t, "dummy-key", writeKeys[0])
+	}
+}
+
+func TestHoneycombDataset(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Host     string
+		Config   *Config
+		Expected string
+	}{
+		{
+			Name:     "dataset in URL",
+			Host:     "honeycomb://url-dataset",
+			Config:   &Config{ServiceName: "my-service", HoneycombDataset: "config-dataset"},
+			Expected: "url-dataset",
+		},
+		{
+			Name:     "dataset in config",
+			Host:     "honeycomb",
+			Config:   &Config{ServiceName: "my-service", HoneycombDataset: "config-dataset"},
+			Expected: "config-dataset",
+		},
+		{
+			Name:     "service name",
+			Host:     "honeycomb",
+			Config:   &Config{ServiceName: "my-service"},
+			Expected: "my-service",
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			exporter, err := SelectTraceExporter(testcase.Host)
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.Expected, honeycombDataset(exporter, testcase.Config))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package occonfig
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// headerTransport adds Config.ExporterHeaders to every request.
+type headerTransport struct {
+	headers   map[string]string
+	transport http.RoundTripper
+}
+
+func (h *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper must not modify the given request
+	clone := new(http.Request)
+	*clone = *req
+	clone.Header = make(http.Header, len(req.Header)+len(h.headers))
+	for key, values := range req.Header {
+		clone.Header[key] = values
+	}
+	for key, value := range h.headers {
+		clone.Header.Set(key, value)
+	}
+	return h.transport.RoundTrip(clone)
+}
+
+// newHTTPClient creates http.Client for the HTTP based exporters.
+// Every exporter that occonfig passes an HTTP client to uses it so that TLS, proxy and header settings are applied in the same way.
+func newHTTPClient(exporter *Exporter, config *Config) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(exporter, config)
+	if err != nil {
+		return nil, err
+	}
+	proxy := http.ProxyFromEnvironment
+	rawProxy := config.ExporterProxy
+	if _, ok := exporter.Options["proxy"]; ok {
+		rawProxy = exporter.Options.Get("proxy")
+	}
+	if rawProxy != "" {
+		proxyURL, err := url.Parse(rawProxy)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse proxy URL %q: %v", rawProxy, err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+	}
+	if len(config.ExporterHeaders) == 0 {
+		return &http.Client{
+			Transport: transport,
+		}, nil
+	}
+	return &http.Client{
+		Transport: &headerTransport{
+			headers:   config.ExporterHeaders,
+			transport: transport,
+		},
+	}, nil
+}
@@ -0,0 +1,114 @@
+package occonfig
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPClientHeaders(t *testing.T) {
+	var lock sync.Mutex
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		headers = append(headers, r.Header)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	exporter, err := SelectTraceExporter(strings.Replace(server.URL, "http://", "zipkin://", 1))
+	assert.Nil(t, err)
+	client, err := newHTTPClient(exporter, &Config{
+		ExporterHeaders: map[string]string{"Authorization": "Bearer token", "X-Tenant": "team-a"},
+	})
+	assert.Nil(t, err)
+	req, _ := http.NewRequest("POST", exporter.Host, strings.NewReader("[]"))
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if assert.Nil(t, err) {
+		res.Body.Close()
+	}
+	assert.Equal(t, "", req.Header.Get("Authorization"))
+
+	lock.Lock()
+	defer lock.Unlock()
+	if assert.Equal(t, 1, len(headers)) {
+		assert.Equal(t, "Bearer token", headers[0].Get("Authorization"))
+		assert.Equal(t, "team-a", headers[0].Get("X-Tenant"))
+		assert.Equal(t, "application/json", headers[0].Get("Content-Type"))
+	}
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	testcases := []struct {
+		Name   string
+		Source string
+		Config *Config
+	}{
+		{
+			Name:   "proxy in config",
+			Source: "zipkin://collector.example.com",
+		},
+		{
+			Name:   "proxy in query",
+			Source: "zipkin://collector.example.com?proxy=",
+			Config: &Config{ExporterProxy: "http://invalid.example.com:1"},
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			var lock sync.Mutex
+			var hosts []string
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				hosts = append(hosts, r.Host)
+				lock.Unlock()
+			}))
+			defer proxy.Close()
+
+			config := testcase.Config
+			source := testcase.Source
+			if config == nil {
+				config = &Config{ExporterProxy: proxy.URL}
+			} else {
+				source += proxy.URL
+			}
+			exporter, err := SelectTraceExporter(source)
+			assert.Nil(t, err)
+			client, err := newHTTPClient(exporter, config)
+			assert.Nil(t, err)
+			res, err := client.Post(exporter.Host, "application/json", strings.NewReader("[]"))
+			if assert.Nil(t, err) {
+				res.Body.Close()
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			assert.Equal(t, []string{"collector.example.com:9411"}, hosts)
+		})
+	}
+}
+
+func TestReadHeaderFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "occonfig")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "token.txt"), []byte("Bearer secret\n"), 0600))
+
+	headers := map[string]string{"Authorization": "file://token.txt", "X-Tenant": "team-a"}
+	config := &Config{ExporterHeaders: headers}
+	assert.Nil(t, readHeaderFiles(config, dir))
+	assert.Equal(t, map[string]string{"Authorization": "Bearer secret", "X-Tenant": "team-a"}, config.ExporterHeaders)
+	// the original map is not modified
+	assert.Equal(t, "file://token.txt", headers["Authorization"])
+
+	config = &Config{ExporterHeaders: map[string]string{"Authorization": "file://missing.txt"}}
+	assert.NotNil(t, readHeaderFiles(config, dir))
+}
@@ -0,0 +1,87 @@
+package occonfig
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Trace ID generators for OC_TRACE_ID_GENERATOR.
+const (
+	// IDGeneratorRandom generates random trace IDs like OpenCensus does by default.
+	IDGeneratorRandom = "random"
+	// IDGeneratorXRay generates trace IDs that AWS X-Ray accepts. It is the default when the trace exporter is X-Ray.
+	IDGeneratorXRay = "xray"
+)
+
+// randomIDGenerator is the same as the default generator of OpenCensus.
+// occonfig uses it to restore the ID generator because OpenCensus doesn't provide a getter.
+type randomIDGenerator struct {
+	lock   sync.Mutex
+	random *rand.Rand
+}
+
+func newRandomIDGenerator() *randomIDGenerator {
+	var seed int64
+	binary.Read(crand.Reader, binary.LittleEndian, &seed)
+	return &randomIDGenerator{
+		random: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (g *randomIDGenerator) read(b []byte) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.random.Read(b)
+}
+
+// NewSpanID returns a non-zero span ID.
+func (g *randomIDGenerator) NewSpanID() [8]byte {
+	var id [8]byte
+	for id == [8]byte{} {
+		g.read(id[:])
+	}
+	return id
+}
+
+// NewTraceID returns a non-zero trace ID.
+func (g *randomIDGenerator) NewTraceID() [16]byte {
+	var id [16]byte
+	for id == [16]byte{} {
+		g.read(id[:])
+	}
+	return id
+}
+
+// XRayIDGenerator generates trace IDs whose first 4 bytes are the current Unix time in seconds.
+// AWS X-Ray rejects trace IDs that don't start with a recent timestamp.
+// It can be used with other exporters:
+//
+//	trace.ApplyConfig(trace.Config{IDGenerator: occonfig.NewXRayIDGenerator()})
+type XRayIDGenerator struct {
+	random *randomIDGenerator
+	now    func() time.Time
+}
+
+// NewXRayIDGenerator returns XRayIDGenerator.
+func NewXRayIDGenerator() *XRayIDGenerator {
+	return &XRayIDGenerator{
+		random: newRandomIDGenerator(),
+		now:    time.Now,
+	}
+}
+
+// NewSpanID returns a random span ID.
+func (g *XRayIDGenerator) NewSpanID() [8]byte {
+	return g.random.NewSpanID()
+}
+
+// NewTraceID returns a trace ID that has the Unix time in the first 4 bytes and 12 random bytes.
+func (g *XRayIDGenerator) NewTraceID() [16]byte {
+	var id [16]byte
+	binary.BigEndian.PutUint32(id[:4], uint32(g.now().Unix()))
+	g.random.read(id[4:])
+	return id
+}
@@ -0,0 +1,109 @@
+package occonfig
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/trace"
+)
+
+func isXRayTraceID(id [16]byte) bool {
+	epoch := int64(binary.BigEndian.Uint32(id[:4]))
+	diff := time.Now().Unix() - epoch
+	return -60 < diff && diff < 60
+}
+
+func TestXRayIDGenerator(t *testing.T) {
+	generator := NewXRayIDGenerator()
+	generator.now = func() time.Time {
+		return time.Unix(0x5d000000, 0)
+	}
+	traceID := generator.NewTraceID()
+	assert.Equal(t, []byte{0x5d, 0x00, 0x00, 0x00}, traceID[:4])
+	assert.NotEqual(t, traceID, generator.NewTraceID())
+	assert.NotEqual(t, [8]byte{}, generator.NewSpanID())
+}
+
+func TestNewTraceConfig(t *testing.T) {
+	testcases := []struct {
+		Name        string
+		IDGenerator string
+		Exporters   []string
+		XRay        bool
+		Keep        bool
+		Error       bool
+	}{
+		{
+			Name:      "default",
+			Exporters: []string{"zap"},
+			Keep:      true,
+		},
+		{
+			Name:      "x-ray exporter",
+			Exporters: []string{"zap", "xray://ap-northeast-1"},
+			XRay:      true,
+		},
+		{
+			Name:        "explicit x-ray",
+			IDGenerator: "xray",
+			Exporters:   []string{"zap"},
+			XRay:        true,
+		},
+		{
+			Name:        "explicit random",
+			IDGenerator: "random",
+			Exporters:   []string{"xray"},
+		},
+		{
+			Name:        "invalid",
+			IDGenerator: "uuid",
+			Error:       true,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			var exporters []*Exporter
+			for _, source := range testcase.Exporters {
+				exporter, err := SelectTraceExporter(source)
+				assert.Nil(t, err)
+				exporters = append(exporters, exporter)
+			}
+			config, err := newTraceConfig(&Config{TraceSampler: 1.0, TraceIDGenerator: testcase.IDGenerator}, exporters)
+			if testcase.Error {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			if testcase.Keep {
+				assert.Nil(t, config.IDGenerator)
+				return
+			}
+			if assert.NotNil(t, config.IDGenerator) {
+				_, isXRay := config.IDGenerator.(*XRayIDGenerator)
+				assert.Equal(t, testcase.XRay, isXRay)
+			}
+		})
+	}
+}
+
+func TestCloseRestoresIDGenerator(t *testing.T) {
+	config := NewConfig()
+	config.TraceExporters = []string{"zap"}
+	config.TraceSampler = 1.0
+	config.TraceIDGenerator = IDGeneratorXRay
+	oc, err := InitWithConfig(context.Background(), config,
+		WithMode(Trace), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger))
+	assert.Nil(t, err)
+	traceID := func() [16]byte {
+		_, span := trace.StartSpan(context.Background(), "test", trace.WithSampler(trace.NeverSample()))
+		span.End()
+		return span.SpanContext().TraceID
+	}
+	assert.True(t, isXRayTraceID(traceID()))
+
+	assert.Nil(t, oc.Close())
+	assert.False(t, isXRayTraceID(traceID()))
+}
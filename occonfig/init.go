@@ -1,29 +1,17 @@
 package occonfig
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/trace"
-	"go.opencensus.io/zpages"
-
-	xray "contrib.go.opencensus.io/exporter/aws"
-	"contrib.go.opencensus.io/exporter/stackdriver"
-	"github.com/Datadog/opencensus-go-exporter-datadog"
-	honeycomb "github.com/honeycombio/opencensus-exporter/honeycomb"
-	openzipkin "github.com/openzipkin/zipkin-go"
-	zipkinHTTP "github.com/openzipkin/zipkin-go/reporter/http"
-	"contrib.go.opencensus.io/exporter/jaeger"
-	"contrib.go.opencensus.io/exporter/prometheus"
-	"contrib.go.opencensus.io/exporter/zipkin"
-	"contrib.go.opencensus.io/exporter/graphite"
-	"github.com/future-architect/futureot/exporters/opencensus-go-exporter-zap"
 )
 
 type Mode int
@@ -34,43 +22,176 @@ const (
 )
 
 type Config struct {
-	ServiceName   string
-	ServiceUrl    string
-	HoneycombKey  string
-	ConfigFile    string
-	TraceExporter string
-	TraceSampler  float64
-	StatsExporter string
-	ZPage         string
+	ServiceName      string
+	ServiceUrl       string
+	HoneycombKey     string
+	HoneycombDataset string
+	HoneycombAPIHost string
+	ConfigFile       string
+	TraceExporters   []string
+	TraceSampler     float64
+	// TraceSamplerParent is how TraceSampler treats the remote parent span. It is empty (the behavior of OpenCensus),
+	// "parentbased" or "ignore". OTEL_TRACES_SAMPLER sets it.
+	TraceSamplerParent string
+	// TraceIDGenerator is "random" or "xray". If it is empty, "xray" is used when one of trace exporters is X-Ray.
+	TraceIDGenerator string
+	StatsExporters   []string
+	ZPage            string
+	ListenPolicy     string
+	// TLS settings for HTTPS exporters. They can be overwritten by URL query (caCert, clientCert, clientKey, insecureSkipVerify).
+	TLSCACert     string
+	TLSClientCert string
+	TLSClientKey  string
+	// TLSInsecureSkipVerify is nil if it is not specified, so that a higher priority setting can set false.
+	TLSInsecureSkipVerify *bool
+	// ExporterHeaders are added to the requests of HTTP exporters. A value like file://token.txt is read from the file.
+	ExporterHeaders map[string]string
+	// ExporterProxy is the proxy URL for HTTP exporters. It can be overwritten by proxy query.
+	ExporterProxy string
+	// ResourceAttributes are sent as the resource of OTLP with service.name (ServiceName). OTEL_RESOURCE_ATTRIBUTES sets them.
+	ResourceAttributes map[string]string
+	// ExporterOptions has exporter specific settings for each scheme (e.g. "graphite").
+	// The URL query of the exporter setting has higher priority.
+	ExporterOptions map[string]url.Values
 }
 
 var getConfigFromCommandLine func() (*Config, error)
 
 type OCConfig interface {
-	Close()
-	StartServer()
+	// Close is same as Shutdown(context.Background()).
+	Close() error
+	// Shutdown stops the HTTP servers after in-flight requests finish, then unregisters and flushes all exporters
+	// in reverse registration order. The trace sampler that was effective before Init is restored. It returns an error that has all failures.
+	// Unregistering exporters and restoring the trace config always finish before it returns. ctx limits only stopping servers
+	// and flushing. If ctx is done before flushing finishes, the error includes ctx.Err().
+	// Calling it twice is safe. The second call returns the first result.
+	Shutdown(ctx context.Context) error
+	// StartServer starts the HTTP servers of Prometheus and ZPage if InitWithConfig is called with WithDeferredServerStart().
+	// Otherwise, the servers are already started by Init and it does nothing.
+	StartServer() error
+	// Endpoints returns the URLs of Prometheus and ZPage that are actually served.
+	Endpoints() []string
+	// Handler returns http.Handler that serves the embedded endpoints
+	// (e.g. prometheus://embedded, embedded:/debug of ZPage) on the application's server.
+	Handler() http.Handler
 }
 
 type occonfigImpl struct {
-	finalizes   []func()
-	startServer func()
+	lock     sync.Mutex
+	closed   bool
+	closeErr error
+	// restores unregister exporters and restore the trace config. finalizes flush exporters.
+	restores    []func() error
+	finalizes   []func() error
+	servers     []*telemetryServer
+	startServer func() error
+}
+
+// multiError has all errors that occurred while closing.
+type multiError []error
+
+func (m multiError) Error() string {
+	var messages []string
+	for _, err := range m {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (m multiError) errorOrNil() error {
+	switch len(m) {
+	case 0:
+		return nil
+	case 1:
+		return m[0]
+	default:
+		return m
+	}
+}
+
+func (f *occonfigImpl) Handler() http.Handler {
+	return embeddedHandler(f.servers)
+}
+
+func (f *occonfigImpl) Endpoints() []string {
+	var result []string
+	for _, server := range f.servers {
+		result = append(result, server.urls()...)
+	}
+	return result
+}
+
+func (f *occonfigImpl) Close() error {
+	return f.Shutdown(context.Background())
+}
+
+func (f *occonfigImpl) Shutdown(ctx context.Context) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed {
+		return f.closeErr
+	}
+	f.closed = true
+	f.startServer = nil
+
+	var errs multiError
+	for i := len(f.servers) - 1; i >= 0; i-- {
+		server := f.servers[i]
+		if server.server == nil {
+			continue
+		}
+		if err := server.server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("Failed to shutdown %s endpoint: %v", server.names(), err))
+		}
+	}
+	// they must finish here. Otherwise, they may overwrite the settings of the next Init
+	for i := len(f.restores) - 1; i >= 0; i-- {
+		if err := f.restores[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	done := make(chan multiError, 1)
+	go func() {
+		var finalizeErrs multiError
+		for i := len(f.finalizes) - 1; i >= 0; i-- {
+			if err := f.finalizes[i](); err != nil {
+				finalizeErrs = append(finalizeErrs, err)
+			}
+		}
+		done <- finalizeErrs
+	}()
+	select {
+	case finalizeErrs := <-done:
+		errs = append(errs, finalizeErrs...)
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err())
+	}
+	f.closeErr = errs.errorOrNil()
+	return f.closeErr
 }
 
-func (f occonfigImpl) Close() {
-	for _, finalizer := range f.finalizes {
-		finalizer()
+func (f *occonfigImpl) StartServer() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.startServer == nil {
+		return nil
 	}
+	startServer := f.startServer
+	f.startServer = nil
+	return startServer()
 }
 
-func (f occonfigImpl) StartServer() {
-	if f.startServer != nil {
-		f.startServer()
+// NewConfig returns an empty Config for InitWithConfig.
+// Empty fields are filled by environment variables, command line options and JSON files.
+func NewConfig() *Config {
+	return &Config{
+		TraceSampler: -1,
 	}
 }
 
-func getConfig() (*Config, error) {
-	wd, _ := os.Getwd()
-	config, err := initByEnvMap(os.Environ())
+func getConfig(explicitConfig *Config, options *initOptions) (*Config, error) {
+	wd := options.workingDir
+	config, err := initByEnv(options.env)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +199,13 @@ func getConfig() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	if getConfigFromCommandLine != nil {
+	// OTEL_* environment variables have lower priority than JSON files
+	otelConfig, err := initByOTelEnv(options.env)
+	if err != nil {
+		return nil, err
+	}
+	config = mergeConfigs(otelConfig, config)
+	if getConfigFromCommandLine != nil && !options.withoutCommandLine {
 		commandConfig, err := getConfigFromCommandLine()
 		if err != nil {
 			return nil, err
@@ -89,6 +216,14 @@ func getConfig() (*Config, error) {
 		}
 		config = mergeConfigs(config, commandConfig)
 	}
+	if explicitConfig != nil {
+		copiedConfig := *explicitConfig
+		explicitConfig, err = readFiles(&copiedConfig, wd)
+		if err != nil {
+			return nil, err
+		}
+		config = mergeConfigs(config, explicitConfig)
+	}
 	if config.TraceSampler < 0 {
 		config.TraceSampler = 1.0
 	}
@@ -98,238 +233,119 @@ func getConfig() (*Config, error) {
 	return config, nil
 }
 
+// Init initializes OpenCensus by environment variables, command line options and JSON files.
 func Init(mode Mode) (OCConfig, error) {
-	finalizer := occonfigImpl{}
-	config, err := getConfig()
+	return InitWithConfig(context.Background(), nil, WithMode(mode))
+}
+
+// InitWithConfig initializes OpenCensus like Init. config has the highest priority over
+// command line options, environment variables and JSON files. Use NewConfig to create it.
+// config can be nil.
+//
+// ctx is used to create exporters and for their API calls.
+//
+// If it returns an error, the exporters, the trace config and the servers that are already set up are undone,
+// so Init can be called again. It is done even if ctx is already done.
+func InitWithConfig(ctx context.Context, config *Config, options ...Option) (result OCConfig, err error) {
+	finalizer := &occonfigImpl{}
+	defer func() {
+		if err != nil {
+			// ctx may be already done. The rollback should not stop halfway
+			if shutdownErr := finalizer.Shutdown(context.Background()); shutdownErr != nil {
+				err = multiError{err, shutdownErr}
+			}
+		}
+	}()
+	o := newInitOptions(options)
+	mode := o.mode
+	config, err = getConfig(config, o)
 	if err != nil {
 		return finalizer, err
 	}
-	isStackDriverInitialized := false
-	isDataDogInitialized := false
-	isZPageInitialized := false
-	if mode&Trace == Trace && config.TraceExporter != "" {
-		exporter, err := SelectTraceExporter(config.TraceExporter)
+	if err := validateListenPolicy(config.ListenPolicy); err != nil {
+		return finalizer, err
+	}
+	instances := newExporterInstances(ctx, config, o.logger, &finalizer.finalizes)
+	var handlers []*ExporterInstance
+	if mode&Trace == Trace && len(config.TraceExporters) > 0 {
+		var exporters []*Exporter
+		for _, traceExporter := range config.TraceExporters {
+			exporter, err := SelectTraceExporter(traceExporter)
+			if err != nil {
+				return finalizer, err
+			}
+			exporters = append(exporters, exporter)
+		}
+		traceConfig, err := newTraceConfig(config, exporters)
 		if err != nil {
 			return finalizer, err
 		}
-		switch exporter.Type {
-		case STACKDRIVER:
-			{
-				sd, err := stackdriver.NewExporter(stackdriver.Options{
-					ProjectID: exporter.Host,
-				})
-				if err != nil {
-					return finalizer, fmt.Errorf("Failed to create the GCP StackDriver exporter: %v", err)
-				}
-				finalizer.finalizes = append(finalizer.finalizes, func() {
-					sd.Flush()
-				})
-				trace.RegisterExporter(sd)
-				if mode&Stats == Stats && config.StatsExporter != "" {
-					view.RegisterExporter(sd)
-					isStackDriverInitialized = true
-				}
-			}
-		case XRAY:
-			{
-				xe, err := xray.NewExporter(xray.WithVersion("latest"))
-				if err != nil {
-					return finalizer, fmt.Errorf("Failed to create the AWS X-Ray exporter: %v", err)
-				}
-				finalizer.finalizes = append(finalizer.finalizes, func() {
-					xe.Flush()
-				})
-				trace.RegisterExporter(xe)
-			}
-		case DATADOG:
-			{
-				dd, err := datadog.NewExporter(datadog.Options{})
-				if err != nil {
-					return finalizer, fmt.Errorf("Failed to create the Datadog exporter: %v", err)
-				}
-				finalizer.finalizes = append(finalizer.finalizes, func() {
-					dd.Stop()
-				})
-				trace.RegisterExporter(dd)
-				if mode&Stats == Stats && config.StatsExporter != "" {
-					view.RegisterExporter(dd)
-					isDataDogInitialized = true
-				}
+		for _, exporter := range exporters {
+			instance, err := instances.get(exporter)
+			if err != nil {
+				return finalizer, err
 			}
-		case HONEYCOMB:
-			{
-				if config.HoneycombKey == "" {
-					return finalizer, errors.New("Honeycomb Write Key is empty")
-				}
-				hc := honeycomb.NewExporter(config.HoneycombKey, "YOUR-DATASET-NAME")
-				hc.SampleFraction = config.TraceSampler
-				trace.RegisterExporter(hc)
+			if err := instance.validate(Trace); err != nil {
+				return finalizer, err
 			}
-		case JAEGER:
-			{
-				je, err := jaeger.NewExporter(jaeger.Options{
-					CollectorEndpoint: exporter.Host,
-					Process: jaeger.Process{
-						ServiceName: config.ServiceName,
-					},
-				})
-				if err != nil {
-					return finalizer, fmt.Errorf("Failed to create the Jaeger exporter: %v", err)
-				}
-				finalizer.finalizes = append(finalizer.finalizes, func() {
-					je.Flush()
-				})
-				trace.RegisterExporter(je)
-			}
-		case ZIPKIN:
-			{
-				localEndpointURI := config.ServiceUrl
-				reporterURI := exporter.Host
-				serviceName := config.ServiceName
-
-				localEndpoint, err := openzipkin.NewEndpoint(serviceName, localEndpointURI)
-				if err != nil {
-					return finalizer, fmt.Errorf("Failed to create Zipkin localEndpoint with URI %q error: %v", localEndpointURI, err)
-				}
-
-				reporter := zipkinHTTP.NewReporter(reporterURI)
-				ze := zipkin.NewExporter(reporter, localEndpoint)
-
-				trace.RegisterExporter(ze)
-			}
-		case ZAP:
-			{
-				trace.RegisterExporter(zap.NewZapTraceExporter())
-			}
-		}
-
-		switch config.TraceSampler {
-		case 0.0:
-			trace.ApplyConfig(trace.Config{
-				DefaultSampler: trace.NeverSample(),
-			})
-		case 1.0:
-			trace.ApplyConfig(trace.Config{
-				DefaultSampler: trace.AlwaysSample(),
-			})
-		default:
-			trace.ApplyConfig(trace.Config{
-				DefaultSampler: trace.ProbabilitySampler(config.TraceSampler),
+			traceExporter := instance.Trace
+			trace.RegisterExporter(traceExporter)
+			finalizer.restores = append(finalizer.restores, func() error {
+				trace.UnregisterExporter(traceExporter)
+				return nil
 			})
 		}
+
+		finalizer.restores = append(finalizer.restores, applyTraceConfig(traceConfig))
 	}
 
-	if mode&Stats == Stats && config.StatsExporter != "" {
-		exporter, err := SelectStatsExporter(config.StatsExporter)
-		if err != nil {
-			return finalizer, err
-		}
-		switch exporter.Type {
-		case STACKDRIVER:
-			{
-				if !isStackDriverInitialized {
-					sd, err := stackdriver.NewExporter(stackdriver.Options{
-						ProjectID: exporter.Host,
-					})
-					if err != nil {
-						return finalizer, fmt.Errorf("Failed to create the GCP StackDriver exporter: %v", err)
-					}
-					finalizer.finalizes = append(finalizer.finalizes, func() {
-						sd.Flush()
-					})
-					view.RegisterExporter(sd)
-				}
+	if mode&Stats == Stats {
+		isRegistered := make(map[*ExporterInstance]bool)
+		for _, statsExporter := range config.StatsExporters {
+			exporter, err := SelectStatsExporter(statsExporter)
+			if err != nil {
+				return finalizer, err
 			}
-		case DATADOG:
-			{
-				if !isDataDogInitialized {
-					dd, err := datadog.NewExporter(datadog.Options{})
-					if err != nil {
-						return finalizer, fmt.Errorf("Failed to create the Datadog exporter: %v", err)
-					}
-					finalizer.finalizes = append(finalizer.finalizes, func() {
-						dd.Stop()
-					})
-					view.RegisterExporter(dd)
-				}
+			instance, err := instances.get(exporter)
+			if err != nil {
+				return finalizer, err
 			}
-		case PROMETHEUS:
-			{
-				pe, err := prometheus.NewExporter(prometheus.Options{
-					Namespace: config.ServiceName,
-				})
-				if err != nil {
-					return finalizer, fmt.Errorf("Failed to create Prometheus exporter: %v", err)
-				}
-
-				view.RegisterExporter(pe)
-				u, _ := url.Parse(exporter.Host)
-				var z *url.URL
-				if config.ZPage != "" {
-					z, err = url.Parse(config.ZPage)
-					if err != nil {
-						return finalizer, fmt.Errorf("Failed to parse ZPage URL: %v", err)
-					}
-					if z.Port() == u.Port() {
-						if z.Path == "/metrics" {
-							return finalizer, fmt.Errorf("ZPage and Prometheus uses same endpoints: %v", err)
-						}
-						isZPageInitialized = true
-					}
-				}
-
-				exit := make(chan bool)
-				finalizer.finalizes = append(finalizer.finalizes, func() {
-					exit <- true
-				})
-				go func() {
-					mux := http.NewServeMux()
-					mux.Handle("/metrics", pe)
-					fmt.Fprintf(os.Stderr, "Start waiting Prometheus access at :%s/metrics\n", u.Port())
-					if z != nil {
-						printZPageInformation(z)
-						zpages.Handle(mux, z.Path)
-					}
-					if err := http.ListenAndServe(":"+u.Port(), mux); err != nil {
-						log.Fatalf("Failed to run Prometheus /metrics endpoint: %v", err)
-					}
-					<-exit
-				}()
+			if err := instance.validate(Stats); err != nil {
+				return finalizer, err
 			}
-		case GRAPHITE:
-			{
-				ge, err := graphite.NewExporter(graphite.Options{Namespace: config.ServiceName})
-				if err != nil {
-					return finalizer, fmt.Errorf("Failed to create Graphite exporter: %v", err)
+			if !isRegistered[instance] {
+				statsExporter := instance.Stats
+				view.RegisterExporter(statsExporter)
+				finalizer.restores = append(finalizer.restores, func() error {
+					view.UnregisterExporter(statsExporter)
+					return nil
+				})
+				if instance.Handler != nil {
+					handlers = append(handlers, instance)
 				}
-				view.RegisterExporter(ge)
+				isRegistered[instance] = true
 			}
 		}
 	}
-	if config.ZPage != "" && !isZPageInitialized {
-		z, err := url.Parse(config.ZPage)
+
+	var zpage *url.URL
+	if config.ZPage != "" {
+		zpage, err = url.Parse(config.ZPage)
 		if err != nil {
 			return finalizer, fmt.Errorf("Failed to parse ZPage URL: %v", err)
 		}
-		exit := make(chan bool)
-		finalizer.finalizes = append(finalizer.finalizes, func() {
-			exit <- true
-		})
-		printZPageInformation(z)
-		go func() {
-			mux := http.NewServeMux()
-			zpages.Handle(mux, z.Path)
-			if err := http.ListenAndServe(":"+z.Port(), mux); err != nil {
-				log.Fatalf("Failed to run ZPage %s endpoint: %v", z.Path, err)
-			}
-			<-exit
-		}()
+	}
+	servers, err := newTelemetryServers(handlers, zpage)
+	if err != nil {
+		return finalizer, err
+	}
+	finalizer.servers = servers
+	if o.deferServerStart {
+		finalizer.startServer = func() error {
+			return startServers(servers, config.ListenPolicy, o.logger)
+		}
+	} else if err := startServers(servers, config.ListenPolicy, o.logger); err != nil {
+		return finalizer, err
 	}
 	return finalizer, nil
 }
-
-func printZPageInformation(u *url.URL) {
-	fmt.Fprintf(os.Stderr, "[OpenCensus] ZPage is initialized. The following URLs are available:\n")
-	fmt.Fprintf(os.Stderr, "    http://localhost:%s%s/rpcz\n", u.Port(), u.Path)
-	fmt.Fprintf(os.Stderr, "    http://localhost:%s%s/tracez\n", u.Port(), u.Path)
-}
@@ -0,0 +1,320 @@
+package occonfig
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/trace"
+)
+
+var testLogger = log.New(ioutil.Discard, "", 0)
+
+func TestGetConfig(t *testing.T) {
+	options := newInitOptions([]Option{
+		WithEnv(map[string]string{
+			"OC_CONFIG_JSON":   "zap.json",
+			"OC_SERVICE_URL":   "http://localhost:8080",
+			"OC_TRACE_SAMPLER": "0.5",
+		}),
+		WithWorkingDir("testdata"),
+		WithoutCommandLine(),
+	})
+	config, err := getConfig(nil, options)
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	assert.Equal(t, "my-service-name-at-zap-json", config.ServiceName)
+	assert.Equal(t, "http://localhost:8080", config.ServiceUrl)
+	assert.Equal(t, []string{"zap"}, config.TraceExporters)
+	assert.InDelta(t, 0.5, config.TraceSampler, 0.01)
+
+	explicitConfig := NewConfig()
+	explicitConfig.ServiceName = "my-service-name-at-config"
+	config, err = getConfig(explicitConfig, options)
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	assert.Equal(t, "my-service-name-at-config", config.ServiceName)
+	assert.Equal(t, "http://localhost:8080", config.ServiceUrl)
+	assert.InDelta(t, 0.5, config.TraceSampler, 0.01)
+}
+
+func TestInitWithConfig(t *testing.T) {
+	config := NewConfig()
+	config.TraceExporters = []string{"zap"}
+	oc, err := InitWithConfig(context.Background(), config,
+		WithMode(Trace), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger))
+	assert.Nil(t, err)
+	oc.Close()
+
+	config.TraceExporters = []string{"unknown"}
+	_, err = InitWithConfig(context.Background(), config,
+		WithMode(Trace), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger))
+	assert.NotNil(t, err)
+}
+
+var finalizedHosts []string
+
+func init() {
+	RegisterExporterFactory("test-finalizer", &ExporterFactory{
+		Name: "Test Finalizer",
+		Mode: Trace | Stats,
+		Parse: func(u *url.URL) (*Exporter, error) {
+			return &Exporter{
+				Type: testCollector,
+				Host: u.Host,
+			}, nil
+		},
+		New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+			host := exporter.Host
+			return &ExporterInstance{
+				Trace: nopTraceExporter{},
+				Finalize: func() error {
+					finalizedHosts = append(finalizedHosts, host)
+					if strings.HasPrefix(host, "fail") {
+						return errors.New("flush error at " + host)
+					}
+					return nil
+				},
+			}, nil
+		},
+	})
+}
+
+func TestClose(t *testing.T) {
+	finalizedHosts = nil
+	config := NewConfig()
+	config.TraceExporters = []string{"test-finalizer://fail1", "test-finalizer://ok", "test-finalizer://fail2"}
+	oc, err := InitWithConfig(context.Background(), config,
+		WithMode(Trace), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger))
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	err = oc.Close()
+	assert.NotNil(t, err)
+	if err != nil {
+		assert.Contains(t, err.Error(), "Test Finalizer")
+		assert.Contains(t, err.Error(), "flush error at fail1")
+		assert.Contains(t, err.Error(), "flush error at fail2")
+	}
+	assert.Equal(t, []string{"fail2", "ok", "fail1"}, finalizedHosts)
+
+	assert.Equal(t, err, oc.Close())
+	assert.Equal(t, []string{"fail2", "ok", "fail1"}, finalizedHosts)
+}
+
+type countingTraceExporter struct {
+	count int
+}
+
+func (c *countingTraceExporter) ExportSpan(s *trace.SpanData) {
+	c.count++
+}
+
+func TestFailedInitRestoresGlobalState(t *testing.T) {
+	exporter := &countingTraceExporter{}
+	RegisterExporterFactory("test-failed-init", &ExporterFactory{
+		Name: "Test Failed Init",
+		Mode: Trace,
+		Parse: func(u *url.URL) (*Exporter, error) {
+			return &Exporter{Type: testCollector}, nil
+		},
+		New: func(ctx context.Context, e *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+			return &ExporterInstance{Trace: exporter}, nil
+		},
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	defer listener.Close()
+
+	defer trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, ctx := range []context.Context{context.Background(), canceledCtx} {
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.NeverSample()})
+		config := NewConfig()
+		config.TraceExporters = []string{"test-failed-init"}
+		config.TraceSampler = 1.0
+		// ZPage fails to listen after the trace exporter and the sampler are applied
+		config.ZPage = "http://" + listener.Addr().String() + "/debug"
+		config.ListenPolicy = ListenFail
+		_, err = InitWithConfig(ctx, config,
+			WithMode(Trace), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger))
+		assert.NotNil(t, err)
+
+		_, span := trace.StartSpan(context.Background(), "test")
+		span.End()
+		assert.False(t, span.SpanContext().IsSampled())
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+		_, span = trace.StartSpan(context.Background(), "test")
+		span.End()
+		assert.Equal(t, 0, exporter.count)
+	}
+}
+
+func TestShutdownWithDoneContext(t *testing.T) {
+	slowExporter := &countingTraceExporter{}
+	nextExporter := &countingTraceExporter{}
+	release := make(chan struct{})
+	flushed := make(chan struct{})
+	RegisterExporterFactory("test-slow-flush", &ExporterFactory{
+		Name: "Test Slow Flush",
+		Mode: Trace,
+		Parse: func(u *url.URL) (*Exporter, error) {
+			return &Exporter{Type: testCollector, Host: u.Host}, nil
+		},
+		New: func(ctx context.Context, e *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+			if e.Host != "slow" {
+				return &ExporterInstance{Trace: nextExporter}, nil
+			}
+			return &ExporterInstance{
+				Trace: slowExporter,
+				Finalize: func() error {
+					<-release
+					close(flushed)
+					return nil
+				},
+			}, nil
+		},
+	})
+	initWith := func(host string) OCConfig {
+		config := NewConfig()
+		config.TraceExporters = []string{"test-slow-flush://" + host}
+		config.TraceSampler = 1.0
+		oc, err := InitWithConfig(context.Background(), config,
+			WithMode(Trace), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger))
+		assert.Nil(t, err)
+		return oc
+	}
+	isSampled := func() bool {
+		_, span := trace.StartSpan(context.Background(), "test")
+		span.End()
+		return span.SpanContext().IsSampled()
+	}
+
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.NeverSample()})
+	defer trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	oc := initWith("slow")
+	if oc == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, oc.Shutdown(ctx))
+	// the exporter is unregistered and the sampler is restored even though flushing hasn't finished
+	assert.False(t, isSampled())
+
+	next := initWith("next")
+	if next == nil {
+		return
+	}
+	close(release)
+	<-flushed
+	assert.True(t, isSampled())
+	assert.Equal(t, 0, slowExporter.count)
+	assert.Equal(t, 1, nextExporter.count)
+	assert.Nil(t, next.Close())
+}
+
+func TestInvalidExporterInstance(t *testing.T) {
+	RegisterExporterFactory("test-invalid-instance", &ExporterFactory{
+		Name: "Test Invalid Instance",
+		Mode: Trace | Stats,
+		Parse: func(u *url.URL) (*Exporter, error) {
+			return &Exporter{Type: testCollector, Host: u.Host}, nil
+		},
+		New: func(ctx context.Context, e *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+			switch e.Host {
+			case "trace-only":
+				return &ExporterInstance{Trace: &countingTraceExporter{}}, nil
+			case "no-endpoint":
+				return &ExporterInstance{Stats: &recordingViewExporter{}, Handler: http.NotFoundHandler()}, nil
+			}
+			return &ExporterInstance{}, nil
+		},
+	})
+	testcases := []struct {
+		Name  string
+		Mode  Mode
+		Trace string
+		Stats string
+		Error string
+	}{
+		{"no trace exporter", Trace, "test-invalid-instance://empty", "", "doesn't return the trace exporter"},
+		{"no stats exporter", Stats, "", "test-invalid-instance://trace-only", "doesn't return the stats exporter"},
+		{"handler without endpoint", Stats, "", "test-invalid-instance://no-endpoint", "without the endpoint"},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			config := NewConfig()
+			if testcase.Trace != "" {
+				config.TraceExporters = []string{testcase.Trace}
+			}
+			if testcase.Stats != "" {
+				config.StatsExporters = []string{testcase.Stats}
+			}
+			_, err := InitWithConfig(context.Background(), config,
+				WithMode(testcase.Mode), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger))
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), "Test Invalid Instance")
+				assert.Contains(t, err.Error(), testcase.Error)
+			}
+		})
+	}
+}
+
+func TestCloseRestoresGlobalState(t *testing.T) {
+	exporter := &countingTraceExporter{}
+	RegisterExporterFactory("test-counter", &ExporterFactory{
+		Name: "Test Counter",
+		Mode: Trace,
+		Parse: func(u *url.URL) (*Exporter, error) {
+			return &Exporter{Type: testCollector}, nil
+		},
+		New: func(ctx context.Context, e *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+			return &ExporterInstance{Trace: exporter}, nil
+		},
+	})
+	initWithSampler := func(sampler float64) OCConfig {
+		config := NewConfig()
+		config.TraceExporters = []string{"test-counter"}
+		config.TraceSampler = sampler
+		oc, err := InitWithConfig(context.Background(), config,
+			WithMode(Trace), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger))
+		assert.Nil(t, err)
+		return oc
+	}
+	isSampled := func() bool {
+		_, span := trace.StartSpan(context.Background(), "test")
+		span.End()
+		return span.SpanContext().IsSampled()
+	}
+
+	outer := initWithSampler(0.0)
+	assert.False(t, isSampled())
+	inner := initWithSampler(1.0)
+	assert.True(t, isSampled())
+	assert.Equal(t, 1, exporter.count)
+
+	assert.Nil(t, inner.Close())
+	assert.False(t, isSampled())
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	assert.True(t, isSampled())
+	assert.Equal(t, 1, exporter.count)
+
+	assert.Nil(t, outer.Close())
+}
@@ -0,0 +1,136 @@
+package occonfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"contrib.go.opencensus.io/exporter/jaeger"
+)
+
+// jaegerHost returns the agent address (host:port) if agent is true, otherwise it returns the collector URL.
+func jaegerHost(u *url.URL, agent bool) string {
+	if agent {
+		host, port := hostPort(u, "localhost", "6831")
+		return fmt.Sprintf("%s:%s", host, port)
+	}
+	host, port := hostPort(u, "localhost", "14268")
+	path := u.Path
+	if path == "" {
+		path = "/api/traces"
+	}
+	return fmt.Sprintf("%s://%s:%s%s", httpScheme(u), host, port, path)
+}
+
+// jaegerOptions builds options from the exporter.
+// Exporter.Host is the agent address (host:port) if agent option is true, otherwise it is the collector URL.
+// Settings for all exporters that the Jaeger exporter can't use are skipped with warnings.
+func jaegerOptions(exporter *Exporter, config *Config, logger *log.Logger) (jaeger.Options, error) {
+	// agent option in JSON is applied after Parse, so the host is decided again
+	if exporter.url != nil {
+		copied := *exporter
+		copied.Host = jaegerHost(exporter.url, exporter.Options.Get("agent") == "true")
+		exporter = &copied
+	}
+	options := jaeger.Options{
+		Username: exporter.Options.Get("username"),
+		Password: exporter.Options.Get("password"),
+		Process: jaeger.Process{
+			ServiceName: config.ServiceName,
+		},
+		OnError: func(err error) {
+			logger.Printf("Failed to export to Jaeger: %v", err)
+		},
+	}
+	if exporter.Options.Get("agent") == "true" {
+		options.AgentEndpoint = exporter.Host
+	} else {
+		options.CollectorEndpoint = httpEndpoint(exporter)
+		// The Jaeger exporter sends spans by http.DefaultClient. Only the system's root CAs and proxy environment variables are available
+		if exporter.Options.Get("headers") != "" || exporter.Options.Get("proxy") != "" {
+			return jaeger.Options{}, errors.New("Jaeger exporter doesn't support headers and proxy options. Use HTTPS_PROXY environment variable for proxy")
+		}
+		if len(config.ExporterHeaders) > 0 || config.ExporterProxy != "" {
+			logger.Printf("[OpenCensus] Warning: Jaeger exporter ignores exporter headers and proxy URL. Use HTTPS_PROXY environment variable for proxy")
+		}
+		if strings.HasPrefix(options.CollectorEndpoint, "https://") {
+			// check only the options of the URL. newTLSConfig also uses the settings for all exporters
+			tlsConfig, err := newTLSConfig(exporter, &Config{})
+			if err != nil {
+				return jaeger.Options{}, err
+			}
+			if tlsConfig != nil {
+				return jaeger.Options{}, errors.New("Jaeger exporter doesn't support caCert, clientCert, clientKey and insecureSkipVerify options")
+			}
+			if config.TLSCACert != "" || config.TLSClientCert != "" || config.TLSClientKey != "" || (config.TLSInsecureSkipVerify != nil && *config.TLSInsecureSkipVerify) {
+				logger.Printf("[OpenCensus] Warning: Jaeger exporter ignores TLS settings (CA certificate, client certificate, insecureSkipVerify). It uses the system's root CAs")
+			}
+		}
+	}
+	for _, tags := range exporter.Options["processTags"] {
+		for _, tag := range splitExporters(tags) {
+			i := strings.Index(tag, "=")
+			if i == -1 {
+				return jaeger.Options{}, fmt.Errorf("Invalid Jaeger process tag %q. It should be key=value", tag)
+			}
+			options.Process.Tags = append(options.Process.Tags, jaeger.StringTag(tag[:i], tag[i+1:]))
+		}
+	}
+	if rawBufferSize := exporter.Options.Get("bufferMaxCount"); rawBufferSize != "" {
+		bufferSize, err := strconv.Atoi(rawBufferSize)
+		if err != nil || bufferSize <= 0 {
+			return jaeger.Options{}, fmt.Errorf("Invalid Jaeger buffer size %q. It should be a positive number", rawBufferSize)
+		}
+		options.BufferMaxCount = bufferSize
+	}
+	return options, nil
+}
+
+var jaegerFactory = &ExporterFactory{
+	Name: "Jaeger",
+	Mode: Trace,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		options := u.Query()
+		if u.User != nil {
+			options.Set("username", u.User.Username())
+			if password, ok := u.User.Password(); ok {
+				options.Set("password", password)
+			}
+		}
+		if u.Scheme == "jaeger+udp" {
+			options.Set("agent", "true")
+		}
+		return &Exporter{
+			Type:    JAEGER,
+			Host:    jaegerHost(u, options.Get("agent") == "true"),
+			Options: options,
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		options, err := jaegerOptions(exporter, config, logger)
+		if err != nil {
+			return nil, err
+		}
+		je, err := jaeger.NewExporter(options)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create the Jaeger exporter: %v", err)
+		}
+		return &ExporterInstance{
+			Trace: je,
+			Finalize: func() error {
+				je.Flush()
+				return nil
+			},
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("jaeger", jaegerFactory)
+	RegisterExporterFactory("jaeger+udp", jaegerFactory)
+	RegisterExporterFactory("jaeger+https", jaegerFactory)
+}
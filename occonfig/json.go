@@ -3,8 +3,12 @@ package occonfig
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"net/url"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -17,6 +21,71 @@ func getString(tree map[string]interface{}, key string) string {
 	return ""
 }
 
+// getStrings accepts a string array or a comma separated string of exporter URLs.
+func getStrings(tree map[string]interface{}, key string) []string {
+	if rawValue, ok := tree[key]; ok {
+		switch value := rawValue.(type) {
+		case string:
+			return splitExporterURLs(value)
+		case []interface{}:
+			var result []string
+			for _, rawItem := range value {
+				if item, ok := rawItem.(string); ok && item != "" {
+					result = append(result, item)
+				}
+			}
+			return result
+		}
+	}
+	return nil
+}
+
+// getExporterOptions reads objects in trace/stats section like {"graphite": {"namespace": "ns"}}.
+// A nested object like {"constLabels": {"env": "prod"}} becomes "env=prod" that is same as the URL query.
+// Options of the same scheme in trace and stats sections are merged per key. The later section has priority.
+func getExporterOptions(tree map[string]interface{}, options map[string]url.Values) {
+	for scheme, rawValue := range tree {
+		object, ok := rawValue.(map[string]interface{})
+		if !ok || scheme == "headers" {
+			continue
+		}
+		values := options[scheme]
+		if values == nil {
+			values = url.Values{}
+			options[scheme] = values
+		}
+		for key, rawItem := range object {
+			values.Del(key)
+			switch item := rawItem.(type) {
+			case []interface{}:
+				for _, rawElement := range item {
+					values.Add(key, optionString(rawElement))
+				}
+			case map[string]interface{}:
+				var pairs []string
+				for name, value := range item {
+					pairs = append(pairs, name+"="+optionString(value))
+				}
+				sort.Strings(pairs)
+				values.Set(key, strings.Join(pairs, ","))
+			default:
+				values.Set(key, optionString(item))
+			}
+		}
+	}
+}
+
+func optionString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
 func parseJSON(content []byte) (config *Config, err error) {
 	config = &Config{
 		TraceSampler: -1,
@@ -31,13 +100,36 @@ func parseJSON(content []byte) (config *Config, err error) {
 		ServiceName:  getString(root, "serviceName"),
 		ServiceUrl:   getString(root, "serviceUrl"),
 		ZPage:        getString(root, "zpage"),
+		ListenPolicy: getString(root, "listenPolicy"),
 		ConfigFile:   getString(root, "extends"),
 		TraceSampler: -1,
 	}
+	if rawTLS, ok := root["tls"]; ok {
+		if tls, ok := rawTLS.(map[string]interface{}); ok {
+			config.TLSCACert = getString(tls, "caCert")
+			config.TLSClientCert = getString(tls, "clientCert")
+			config.TLSClientKey = getString(tls, "clientKey")
+			if insecureSkipVerify, ok := tls["insecureSkipVerify"].(bool); ok {
+				config.TLSInsecureSkipVerify = &insecureSkipVerify
+			}
+		}
+	}
+	config.ExporterProxy = getString(root, "proxy")
+	exporterOptions := make(map[string]url.Values)
 	if rawTrace, ok := root["trace"]; ok {
 		if trace, ok := rawTrace.(map[string]interface{}); ok {
 			config.HoneycombKey = getString(trace, "honeycombWriteKey")
-			config.TraceExporter = getString(trace, "exporter")
+			config.HoneycombDataset = getString(trace, "honeycombDataset")
+			config.HoneycombAPIHost = getString(trace, "honeycombApiHost")
+			config.TraceExporters = getStrings(trace, "exporter")
+			config.TraceIDGenerator = getString(trace, "idGenerator")
+			if rawHeaders, ok := trace["headers"].(map[string]interface{}); ok {
+				config.ExporterHeaders = make(map[string]string)
+				for key, value := range rawHeaders {
+					config.ExporterHeaders[key] = optionString(value)
+				}
+			}
+			getExporterOptions(trace, exporterOptions)
 			if rawSampler, ok := trace["sampler"]; ok {
 				switch value := rawSampler.(type) {
 				case string:
@@ -56,11 +148,15 @@ func parseJSON(content []byte) (config *Config, err error) {
 			}
 		}
 	}
-	if rawTrace, ok := root["stats"]; ok {
-		if trace, ok := rawTrace.(map[string]interface{}); ok {
-			config.StatsExporter = getString(trace, "exporter")
+	if rawStats, ok := root["stats"]; ok && config != nil {
+		if stats, ok := rawStats.(map[string]interface{}); ok {
+			config.StatsExporters = getStrings(stats, "exporter")
+			getExporterOptions(stats, exporterOptions)
 		}
 	}
+	if len(exporterOptions) > 0 && config != nil {
+		config.ExporterOptions = exporterOptions
+	}
 	return
 }
 
@@ -78,16 +174,91 @@ func selectNumber(a, b float64) float64 {
 	return b
 }
 
+func selectStrings(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	return b
+}
+
+// selectExporterOptions merges options of each scheme. Values in b have higher priority.
+func selectExporterOptions(a, b map[string]url.Values) map[string]url.Values {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	result := make(map[string]url.Values)
+	for _, options := range []map[string]url.Values{a, b} {
+		for scheme, values := range options {
+			if result[scheme] == nil {
+				result[scheme] = url.Values{}
+			}
+			for key, value := range values {
+				result[scheme][key] = value
+			}
+		}
+	}
+	return result
+}
+
+// selectHeaders merges headers. Values in b have higher priority.
+func selectHeaders(a, b map[string]string) map[string]string {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	result := make(map[string]string)
+	for key, value := range a {
+		result[key] = value
+	}
+	for key, value := range b {
+		result[key] = value
+	}
+	return result
+}
+
+// selectSamplerParent returns TraceSamplerParent of the config whose TraceSampler is used.
+func selectSamplerParent(low, high *Config) string {
+	if high.TraceSampler < 0 {
+		return low.TraceSamplerParent
+	}
+	return high.TraceSamplerParent
+}
+
+func selectOptionalBool(a, b *bool) *bool {
+	if b == nil {
+		return a
+	}
+	return b
+}
+
 func mergeConfigs(low, high *Config) *Config {
 	return &Config{
-		ServiceName:   selectString(low.ServiceName, high.ServiceName),
-		ServiceUrl:    selectString(low.ServiceUrl, high.ServiceUrl),
-		ZPage:         selectString(low.ZPage, high.ZPage),
-		ConfigFile:    selectString(low.ConfigFile, high.ConfigFile),
-		TraceExporter: selectString(low.TraceExporter, high.TraceExporter),
-		TraceSampler:  selectNumber(low.TraceSampler, high.TraceSampler),
-		HoneycombKey:  selectString(low.HoneycombKey, high.HoneycombKey),
-		StatsExporter: selectString(low.StatsExporter, high.StatsExporter),
+		ServiceName:           selectString(low.ServiceName, high.ServiceName),
+		ServiceUrl:            selectString(low.ServiceUrl, high.ServiceUrl),
+		ZPage:                 selectString(low.ZPage, high.ZPage),
+		ListenPolicy:          selectString(low.ListenPolicy, high.ListenPolicy),
+		TLSCACert:             selectString(low.TLSCACert, high.TLSCACert),
+		TLSClientCert:         selectString(low.TLSClientCert, high.TLSClientCert),
+		TLSClientKey:          selectString(low.TLSClientKey, high.TLSClientKey),
+		TLSInsecureSkipVerify: selectOptionalBool(low.TLSInsecureSkipVerify, high.TLSInsecureSkipVerify),
+		ExporterHeaders:       selectHeaders(low.ExporterHeaders, high.ExporterHeaders),
+		ExporterProxy:         selectString(low.ExporterProxy, high.ExporterProxy),
+		ConfigFile:            selectString(low.ConfigFile, high.ConfigFile),
+		TraceExporters:        selectStrings(low.TraceExporters, high.TraceExporters),
+		TraceSampler:          selectNumber(low.TraceSampler, high.TraceSampler),
+		TraceSamplerParent:    selectSamplerParent(low, high),
+		TraceIDGenerator:      selectString(low.TraceIDGenerator, high.TraceIDGenerator),
+		HoneycombKey:          selectString(low.HoneycombKey, high.HoneycombKey),
+		HoneycombDataset:      selectString(low.HoneycombDataset, high.HoneycombDataset),
+		HoneycombAPIHost:      selectString(low.HoneycombAPIHost, high.HoneycombAPIHost),
+		StatsExporters:        selectStrings(low.StatsExporters, high.StatsExporters),
+		ResourceAttributes:    selectHeaders(low.ResourceAttributes, high.ResourceAttributes),
+		ExporterOptions:       selectExporterOptions(low.ExporterOptions, high.ExporterOptions),
 	}
 }
 
@@ -103,11 +274,85 @@ func readHoneycombKey(config *Config, currentFolder string) error {
 	return nil
 }
 
+// resolveTLSFiles makes relative paths in JSON file relative to the JSON file.
+func resolveTLSFiles(config *Config, currentFolder string) {
+	for _, path := range []*string{&config.TLSCACert, &config.TLSClientCert, &config.TLSClientKey} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(currentFolder, *path)
+		}
+	}
+}
+
+// exporterFileOptions are exporter options that have file paths.
+var exporterFileOptions = []string{"caCert", "clientCert", "clientKey", "certFile", "keyFile", "credentials"}
+
+// resolveExporterFiles makes relative paths in exporter options and URL queries in JSON file relative to the JSON file.
+func resolveExporterFiles(config *Config, currentFolder string) {
+	resolve := func(values url.Values) bool {
+		changed := false
+		for _, key := range exporterFileOptions {
+			for i, path := range values[key] {
+				if path != "" && !filepath.IsAbs(path) {
+					values[key][i] = filepath.Join(currentFolder, path)
+					changed = true
+				}
+			}
+		}
+		return changed
+	}
+	for _, values := range config.ExporterOptions {
+		resolve(values)
+	}
+	for _, exporters := range [][]string{config.TraceExporters, config.StatsExporters} {
+		for i, exporter := range exporters {
+			u, err := url.Parse(exporter)
+			if err != nil {
+				// SelectTraceExporter/SelectStatsExporter reports it
+				continue
+			}
+			query := u.Query()
+			if resolve(query) {
+				u.RawQuery = query.Encode()
+				exporters[i] = u.String()
+			}
+		}
+	}
+}
+
+// readHeaderFiles reads header values like file://token.txt from the files.
+// The map is replaced because it may be shared with the Config that is passed to InitWithConfig.
+func readHeaderFiles(config *Config, currentFolder string) error {
+	if len(config.ExporterHeaders) == 0 {
+		return nil
+	}
+	headers := make(map[string]string)
+	for key, value := range config.ExporterHeaders {
+		if strings.HasPrefix(value, "file://") {
+			headerPath := strings.TrimPrefix(value, "file://")
+			if !filepath.IsAbs(headerPath) {
+				headerPath = filepath.Join(currentFolder, headerPath)
+			}
+			content, err := ioutil.ReadFile(headerPath)
+			if err != nil {
+				return fmt.Errorf("Failed to read header %s: %v", key, err)
+			}
+			value = strings.TrimSpace(string(content))
+		}
+		headers[key] = value
+	}
+	config.ExporterHeaders = headers
+	return nil
+}
+
 func readFiles(config *Config, currentFolder string) (*Config, error) {
 	err := readHoneycombKey(config, currentFolder)
 	if err != nil {
 		return nil, err
 	}
+	err = readHeaderFiles(config, currentFolder)
+	if err != nil {
+		return nil, err
+	}
 	for config.ConfigFile != "" {
 		filePath := filepath.Clean(filepath.Join(currentFolder, config.ConfigFile))
 		currentFolder = filepath.Dir(filePath)
@@ -123,6 +368,12 @@ func readFiles(config *Config, currentFolder string) (*Config, error) {
 		if err != nil {
 			return nil, err
 		}
+		err = readHeaderFiles(jsonConfig, currentFolder)
+		if err != nil {
+			return nil, err
+		}
+		resolveTLSFiles(jsonConfig, currentFolder)
+		resolveExporterFiles(jsonConfig, currentFolder)
 		config.ConfigFile = ""
 		config = mergeConfigs(jsonConfig, config)
 	}
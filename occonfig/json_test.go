@@ -2,22 +2,35 @@ package occonfig
 
 import (
 	"github.com/stretchr/testify/assert"
+	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestParseJson(t *testing.T) {
 	testcases := []struct {
-		Name          string
-		Source        string
-		ServiceName   string
-		ServiceUrl    string
-		ConfigFile    string
-		HoneycombKey  string
-		TraceExporter string
-		TraceSampler  float64
-		StatsExporter string
-		ZPage         string
+		Name                  string
+		Source                string
+		ServiceName           string
+		ServiceUrl            string
+		ConfigFile            string
+		HoneycombKey          string
+		HoneycombDataset      string
+		HoneycombAPIHost      string
+		TraceExporters        []string
+		TraceSampler          float64
+		TraceIDGenerator      string
+		StatsExporters        []string
+		ExporterOptions       map[string]url.Values
+		ZPage                 string
+		ListenPolicy          string
+		TLSCACert             string
+		TLSClientCert         string
+		TLSClientKey          string
+		TLSInsecureSkipVerify *bool
+		ExporterHeaders       map[string]string
+		ExporterProxy         string
 	}{
 		{
 			Name:         "serviceName test",
@@ -43,6 +56,28 @@ func TestParseJson(t *testing.T) {
 			ZPage:        "http://:8080/debug",
 			TraceSampler: -1,
 		},
+		{
+			Name:         "listenPolicy test",
+			Source:       `{"listenPolicy": "warn"}`,
+			ListenPolicy: "warn",
+			TraceSampler: -1,
+		},
+		{
+			Name:                  "tls test",
+			Source:                `{"tls": {"caCert": "ca.crt", "clientCert": "client.crt", "clientKey": "client.key", "insecureSkipVerify": true} }`,
+			TLSCACert:             "ca.crt",
+			TLSClientCert:         "client.crt",
+			TLSClientKey:          "client.key",
+			TLSInsecureSkipVerify: boolPtr(true),
+			TraceSampler:          -1,
+		},
+		{
+			Name:            "exporter-headers test",
+			Source:          `{"proxy": "http://proxy:8080", "trace": {"headers": {"Authorization": "Bearer token", "X-Tenant": "team-a"}} }`,
+			ExporterHeaders: map[string]string{"Authorization": "Bearer token", "X-Tenant": "team-a"},
+			ExporterProxy:   "http://proxy:8080",
+			TraceSampler:    -1,
+		},
 		{
 			Name:         "configJson test",
 			Source:       `{"extends": "./testdata/config.json"}`,
@@ -56,10 +91,29 @@ func TestParseJson(t *testing.T) {
 			TraceSampler: -1,
 		},
 		{
-			Name:          "trace-exporter test",
-			Source:        `{"trace": {"exporter": "jaeger://localhost:6831"} }`,
-			TraceExporter: "jaeger://localhost:6831",
-			TraceSampler:  -1,
+			Name:             "honeycombDataset test",
+			Source:           `{"trace": {"honeycombDataset": "my-dataset", "honeycombApiHost": "http://localhost:8080"} }`,
+			HoneycombDataset: "my-dataset",
+			HoneycombAPIHost: "http://localhost:8080",
+			TraceSampler:     -1,
+		},
+		{
+			Name:           "trace-exporter test",
+			Source:         `{"trace": {"exporter": "jaeger://localhost:6831"} }`,
+			TraceExporters: []string{"jaeger://localhost:6831"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "trace-exporter test (array)",
+			Source:         `{"trace": {"exporter": ["jaeger://localhost:6831", "zap"]} }`,
+			TraceExporters: []string{"jaeger://localhost:6831", "zap"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:             "trace-id-generator test",
+			Source:           `{"trace": {"idGenerator": "xray"} }`,
+			TraceIDGenerator: "xray",
+			TraceSampler:     -1,
 		},
 		{
 			Name:         "trace-sampler test (1)",
@@ -77,10 +131,43 @@ func TestParseJson(t *testing.T) {
 			TraceSampler: 0.25,
 		},
 		{
-			Name:          "stats-exporter test",
-			Source:        `{"stats": {"exporter": "p8s://localhost:8888"} }`,
-			StatsExporter: "p8s://localhost:8888",
-			TraceSampler:  -1,
+			Name:           "stats-exporter test",
+			Source:         `{"stats": {"exporter": "p8s://localhost:8888"} }`,
+			StatsExporters: []string{"p8s://localhost:8888"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "stats-exporter test (array)",
+			Source:         `{"stats": {"exporter": ["p8s://localhost:8888", "sd://my-project"]} }`,
+			StatsExporters: []string{"p8s://localhost:8888", "sd://my-project"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "exporter options test (object)",
+			Source:         `{"stats": {"exporter": "prometheus", "prometheus": {"constLabels": {"team": "a", "env": "prod"}}} }`,
+			StatsExporters: []string{"prometheus"},
+			ExporterOptions: map[string]url.Values{
+				"prometheus": {"constLabels": {"env=prod,team=a"}},
+			},
+			TraceSampler: -1,
+		},
+		{
+			Name:           "exporter options test",
+			Source:         `{"trace": {"dd": {"service": "svc"}}, "stats": {"exporter": "graphite", "graphite": {"namespace": "ns", "tags": ["env=prod", "team=a"], "interval": "10s"}} }`,
+			StatsExporters: []string{"graphite"},
+			ExporterOptions: map[string]url.Values{
+				"dd":       {"service": {"svc"}},
+				"graphite": {"namespace": {"ns"}, "tags": {"env=prod", "team=a"}, "interval": {"10s"}},
+			},
+			TraceSampler: -1,
+		},
+		{
+			Name:   "exporter options test (trace and stats)",
+			Source: `{"trace": {"stackdriver": {"credentials": "key.json", "location": "trace"}}, "stats": {"stackdriver": {"metricPrefix": "custom", "location": "stats"}}}`,
+			ExporterOptions: map[string]url.Values{
+				"stackdriver": {"credentials": {"key.json"}, "metricPrefix": {"custom"}, "location": {"stats"}},
+			},
+			TraceSampler: -1,
 		},
 	}
 	for _, testcase := range testcases {
@@ -93,15 +180,41 @@ func TestParseJson(t *testing.T) {
 			assert.Equal(t, testcase.ServiceName, result.ServiceName)
 			assert.Equal(t, testcase.ServiceUrl, result.ServiceUrl)
 			assert.Equal(t, testcase.ZPage, result.ZPage)
+			assert.Equal(t, testcase.ListenPolicy, result.ListenPolicy)
+			assert.Equal(t, testcase.TLSCACert, result.TLSCACert)
+			assert.Equal(t, testcase.TLSClientCert, result.TLSClientCert)
+			assert.Equal(t, testcase.TLSClientKey, result.TLSClientKey)
+			assert.Equal(t, testcase.TLSInsecureSkipVerify, result.TLSInsecureSkipVerify)
+			assert.Equal(t, testcase.ExporterHeaders, result.ExporterHeaders)
+			assert.Equal(t, testcase.ExporterProxy, result.ExporterProxy)
 			assert.Equal(t, testcase.ConfigFile, result.ConfigFile)
 			assert.Equal(t, testcase.HoneycombKey, result.HoneycombKey)
-			assert.Equal(t, testcase.TraceExporter, result.TraceExporter)
+			assert.Equal(t, testcase.HoneycombDataset, result.HoneycombDataset)
+			assert.Equal(t, testcase.HoneycombAPIHost, result.HoneycombAPIHost)
+			assert.Equal(t, testcase.TraceExporters, result.TraceExporters)
+			assert.Equal(t, testcase.TraceIDGenerator, result.TraceIDGenerator)
 			assert.InDelta(t, testcase.TraceSampler, result.TraceSampler, 0.01)
-			assert.Equal(t, testcase.StatsExporter, result.StatsExporter)
+			assert.Equal(t, testcase.StatsExporters, result.StatsExporters)
+			assert.Equal(t, testcase.ExporterOptions, result.ExporterOptions)
 		})
 	}
 }
 
+func TestMergeExporterOptions(t *testing.T) {
+	low := &Config{ExporterOptions: map[string]url.Values{
+		"graphite": {"namespace": {"low"}, "interval": {"10s"}},
+		"dd":       {"service": {"low"}},
+	}}
+	high := &Config{ExporterOptions: map[string]url.Values{
+		"graphite": {"namespace": {"high"}},
+	}}
+	result := mergeConfigs(low, high)
+	assert.Equal(t, map[string]url.Values{
+		"graphite": {"namespace": {"high"}, "interval": {"10s"}},
+		"dd":       {"service": {"low"}},
+	}, result.ExporterOptions)
+}
+
 func TestReadJsonConfig1(t *testing.T) {
 	config := &Config{
 		ConfigFile: "./testdata/config.json",
@@ -114,7 +227,7 @@ func TestReadJsonConfig1(t *testing.T) {
 	}
 	assert.Equal(t, "my-service-name-at-file", config.ServiceName)
 	assert.Equal(t, "dummy key at file", config.HoneycombKey)
-	assert.Equal(t, "stackdriver://demo-project-id", config.TraceExporter)
+	assert.Equal(t, []string{"stackdriver://demo-project-id"}, config.TraceExporters)
 }
 
 func TestReadJsonConfig2(t *testing.T) {
@@ -147,3 +260,43 @@ func TestReadJsonWithExtendsConfig(t *testing.T) {
 	}
 	assert.Equal(t, "my-service-name-at-extends-json", config.ServiceName)
 }
+
+func TestReadJsonTLSFiles(t *testing.T) {
+	config := &Config{
+		ConfigFile: "./testdata/tls.json",
+	}
+	wd, _ := os.Getwd()
+	config, err := readFiles(config, wd)
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	assert.Equal(t, filepath.Join(wd, "testdata", "certs", "ca.crt"), config.TLSCACert)
+	assert.Equal(t, "/etc/certs/client.crt", config.TLSClientCert)
+}
+
+func TestReadJsonExporterFiles(t *testing.T) {
+	config := &Config{
+		ConfigFile: "./testdata/exporter_files.json",
+	}
+	wd, _ := os.Getwd()
+	config, err := readFiles(config, wd)
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	assert.Equal(t, filepath.Join(wd, "testdata", "keys", "sa.json"), config.ExporterOptions["stackdriver"].Get("credentials"))
+	assert.Equal(t, filepath.Join(wd, "testdata", "certs", "server.crt"), config.ExporterOptions["prometheus"].Get("certFile"))
+	assert.Equal(t, "/etc/certs/server.key", config.ExporterOptions["prometheus"].Get("keyFile"))
+
+	exporter, err := SelectTraceExporter(config.TraceExporters[0])
+	assert.Nil(t, err)
+	if exporter != nil {
+		assert.Equal(t, filepath.Join(wd, "testdata", "certs", "ca.crt"), exporter.Options.Get("caCert"))
+	}
+	exporter, err = SelectTraceExporter(config.TraceExporters[1])
+	assert.Nil(t, err)
+	if exporter != nil {
+		assert.Equal(t, "/etc/keys/sa.json", exporter.Options.Get("credentials"))
+	}
+}
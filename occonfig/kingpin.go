@@ -7,14 +7,24 @@ import (
 )
 
 type KingpinResult struct {
-	ServiceName   string
-	ServiceUrl    *url.URL
-	HoneycombKey  string
-	ConfigFile    string
-	ZPage         *url.URL
-	TraceExporter *url.URL
-	TraceSampler  string
-	StatsExporter *url.URL
+	ServiceName           string
+	ServiceUrl            *url.URL
+	HoneycombKey          string
+	HoneycombDataset      string
+	HoneycombAPIHost      *url.URL
+	ConfigFile            string
+	ZPage                 *url.URL
+	ListenPolicy          string
+	TLSCACert             string
+	TLSClientCert         string
+	TLSClientKey          string
+	TLSInsecureSkipVerify *bool
+	ExporterHeaders       []string
+	ExporterProxy         *url.URL
+	TraceExporters        []string
+	TraceSampler          string
+	TraceIDGenerator      string
+	StatsExporters        []string
 }
 
 func UseKingpin(mode Mode, application ...*kingpin.Application) {
@@ -35,19 +45,39 @@ func InitApplication(application *kingpin.Application, mode Mode) *KingpinResult
 		ExistingFileVar(&result.ConfigFile)
 	application.Flag("oc-zpage", "ZPage in-process debug console url (e.g. http://:8888/debug").
 		URLVar(&result.ZPage)
+	application.Flag("oc-listen-policy", "Policy when the port of Prometheus or ZPage is not available ('fail'(default), 'warn', 'free-port')").
+		EnumVar(&result.ListenPolicy, ListenFail, ListenWarn, ListenFreePort)
+	application.Flag("oc-tls-ca-cert", "CA certificate file path to verify HTTPS exporters").
+		ExistingFileVar(&result.TLSCACert)
+	application.Flag("oc-tls-client-cert", "Client certificate file path for HTTPS exporters (mTLS)").
+		ExistingFileVar(&result.TLSClientCert)
+	application.Flag("oc-tls-client-key", "Client key file path for HTTPS exporters (mTLS)").
+		ExistingFileVar(&result.TLSClientKey)
+	application.Flag("oc-tls-insecure-skip-verify", "Skip verifying server certificates of HTTPS exporters (for development)").
+		SetValue(&optionalBoolValue{&result.TLSInsecureSkipVerify})
+	application.Flag("oc-exporter-headers", "Comma separated headers for HTTP exporters. It can be specified multiple times (e.g. Authorization=file://token.txt,X-Tenant=team-a)").
+		StringsVar(&result.ExporterHeaders)
+	application.Flag("oc-exporter-proxy", "Proxy URL for HTTP exporters").
+		URLVar(&result.ExporterProxy)
 
 	if mode&Trace == Trace {
-		application.Flag("oc-trace-exporter", "Trace exporter (e.g. stackdriver://demo-project-id, jaeger://localhost:6831").
-			URLVar(&result.TraceExporter)
+		application.Flag("oc-trace-exporter", "Trace exporter. It can be specified multiple times or comma separated (e.g. stackdriver://demo-project-id, jaeger://localhost:6831,zap").
+			StringsVar(&result.TraceExporters)
 		application.Flag("oc-trace-sampler", "Trace sampling rate ('always'(default), 'never', '0-1'").
 			StringVar(&result.TraceSampler)
+		application.Flag("oc-trace-id-generator", "Trace ID generator ('random', 'xray'). Default is 'xray' if trace exporter is X-Ray, otherwise 'random'").
+			EnumVar(&result.TraceIDGenerator, IDGeneratorRandom, IDGeneratorXRay)
 		application.Flag("oc-honeycomb-write-key", "Honeycomb.io write key or file path(file://) (it is needed when trace exporter is honeycomb)").
 			ExistingFileVar(&result.HoneycombKey)
+		application.Flag("oc-honeycomb-dataset", "Honeycomb.io dataset name (default is service name)").
+			StringVar(&result.HoneycombDataset)
+		application.Flag("oc-honeycomb-api-host", "Honeycomb.io API host (default is https://api.honeycomb.io/)").
+			URLVar(&result.HoneycombAPIHost)
 	}
 
 	if mode&Stats == Stats {
-		application.Flag("oc-stats-exporter", "Stats exporter (e.g. stackdriver://demo-project-id, prometheus://localhost:8888").
-			URLVar(&result.StatsExporter)
+		application.Flag("oc-stats-exporter", "Stats exporter. It can be specified multiple times or comma separated (e.g. stackdriver://demo-project-id, prometheus://:8888,graphite").
+			StringsVar(&result.StatsExporters)
 	}
 
 	getConfigFromCommandLine = func() (*Config, error) {
@@ -59,18 +89,38 @@ func InitApplication(application *kingpin.Application, mode Mode) *KingpinResult
 
 func parseKingpinResult(kingpinResult *KingpinResult) (config *Config, err error) {
 	config = &Config{
-		ServiceName:  kingpinResult.ServiceName,
-		HoneycombKey: kingpinResult.HoneycombKey,
-		ConfigFile:   kingpinResult.ConfigFile,
+		ServiceName:           kingpinResult.ServiceName,
+		HoneycombKey:          kingpinResult.HoneycombKey,
+		HoneycombDataset:      kingpinResult.HoneycombDataset,
+		ConfigFile:            kingpinResult.ConfigFile,
+		ListenPolicy:          kingpinResult.ListenPolicy,
+		TraceIDGenerator:      kingpinResult.TraceIDGenerator,
+		TLSCACert:             kingpinResult.TLSCACert,
+		TLSClientCert:         kingpinResult.TLSClientCert,
+		TLSClientKey:          kingpinResult.TLSClientKey,
+		TLSInsecureSkipVerify: kingpinResult.TLSInsecureSkipVerify,
 	}
 	if kingpinResult.ServiceUrl != nil {
 		config.ServiceUrl = kingpinResult.ServiceUrl.String()
 	}
+	if kingpinResult.HoneycombAPIHost != nil {
+		config.HoneycombAPIHost = kingpinResult.HoneycombAPIHost.String()
+	}
+	for _, rawHeaders := range kingpinResult.ExporterHeaders {
+		headers, e := parseHeaders(rawHeaders)
+		if e != nil {
+			return nil, e
+		}
+		config.ExporterHeaders = selectHeaders(config.ExporterHeaders, headers)
+	}
+	if kingpinResult.ExporterProxy != nil {
+		config.ExporterProxy = kingpinResult.ExporterProxy.String()
+	}
 	if kingpinResult.ZPage != nil {
 		config.ZPage = kingpinResult.ZPage.String()
 	}
-	if kingpinResult.TraceExporter != nil {
-		config.TraceExporter = kingpinResult.TraceExporter.String()
+	for _, traceExporter := range kingpinResult.TraceExporters {
+		config.TraceExporters = append(config.TraceExporters, splitExporterURLs(traceExporter)...)
 	}
 	s, e := SelectSampler(kingpinResult.TraceSampler)
 	if e != nil {
@@ -79,8 +129,8 @@ func parseKingpinResult(kingpinResult *KingpinResult) (config *Config, err error
 	} else {
 		config.TraceSampler = s
 	}
-	if kingpinResult.StatsExporter != nil {
-		config.StatsExporter = kingpinResult.StatsExporter.String()
+	for _, statsExporter := range kingpinResult.StatsExporters {
+		config.StatsExporters = append(config.StatsExporters, splitExporterURLs(statsExporter)...)
 	}
 	return
 }
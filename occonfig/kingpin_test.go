@@ -9,16 +9,26 @@ import (
 
 func TestInitKingPin(t *testing.T) {
 	testcases := []struct {
-		Name          string
-		Params        []string
-		ServiceName   string
-		ServiceUrl    string
-		ConfigFile    string
-		HoneycombKey  string
-		TraceExporter string
-		TraceSampler  float64
-		StatsExporter string
-		ZPage         string
+		Name                  string
+		Params                []string
+		ServiceName           string
+		ServiceUrl            string
+		ConfigFile            string
+		HoneycombKey          string
+		HoneycombDataset      string
+		HoneycombAPIHost      string
+		TraceExporters        []string
+		TraceSampler          float64
+		TraceIDGenerator      string
+		StatsExporters        []string
+		ZPage                 string
+		ListenPolicy          string
+		TLSCACert             string
+		TLSClientCert         string
+		TLSClientKey          string
+		TLSInsecureSkipVerify *bool
+		ExporterHeaders       map[string]string
+		ExporterProxy         string
 	}{
 		{
 			Name:         "service-name test",
@@ -50,6 +60,34 @@ func TestInitKingPin(t *testing.T) {
 			ZPage:        "http://:8888/debug",
 			TraceSampler: -1,
 		},
+		{
+			Name:         "listen-policy test",
+			Params:       []string{"--oc-listen-policy", "free-port"},
+			ListenPolicy: "free-port",
+			TraceSampler: -1,
+		},
+		{
+			Name:                  "tls test",
+			Params:                []string{"--oc-tls-ca-cert", "./testdata/honeycomb.key", "--oc-tls-client-cert", "./testdata/honeycomb.key", "--oc-tls-client-key", "./testdata/honeycomb.key", "--oc-tls-insecure-skip-verify"},
+			TLSCACert:             "./testdata/honeycomb.key",
+			TLSClientCert:         "./testdata/honeycomb.key",
+			TLSClientKey:          "./testdata/honeycomb.key",
+			TLSInsecureSkipVerify: boolPtr(true),
+			TraceSampler:          -1,
+		},
+		{
+			Name:                  "tls test (no-insecure-skip-verify)",
+			Params:                []string{"--no-oc-tls-insecure-skip-verify"},
+			TLSInsecureSkipVerify: boolPtr(false),
+			TraceSampler:          -1,
+		},
+		{
+			Name:            "exporter-headers test",
+			Params:          []string{"--oc-exporter-headers", "Authorization=Bearer token,X-Tenant=team-a", "--oc-exporter-headers", "X-Region=tokyo", "--oc-exporter-proxy", "http://proxy:8080"},
+			ExporterHeaders: map[string]string{"Authorization": "Bearer token", "X-Tenant": "team-a", "X-Region": "tokyo"},
+			ExporterProxy:   "http://proxy:8080",
+			TraceSampler:    -1,
+		},
 		{
 			Name:         "honeycomb-write-key test",
 			Params:       []string{"--oc-honeycomb-write-key", "./testdata/honeycomb.key"},
@@ -57,10 +95,35 @@ func TestInitKingPin(t *testing.T) {
 			TraceSampler: -1,
 		},
 		{
-			Name:          "trace-exporter test",
-			Params:        []string{"--oc-trace-exporter", "jaeger://localhost:6831"},
-			TraceExporter: "jaeger://localhost:6831",
-			TraceSampler:  -1,
+			Name:             "honeycomb-dataset test",
+			Params:           []string{"--oc-honeycomb-dataset", "my-dataset", "--oc-honeycomb-api-host", "http://localhost:8080"},
+			HoneycombDataset: "my-dataset",
+			HoneycombAPIHost: "http://localhost:8080",
+			TraceSampler:     -1,
+		},
+		{
+			Name:           "trace-exporter test",
+			Params:         []string{"--oc-trace-exporter", "jaeger://localhost:6831"},
+			TraceExporters: []string{"jaeger://localhost:6831"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "trace-exporter test (comma separated)",
+			Params:         []string{"--oc-trace-exporter", "jaeger://localhost:6831,zap"},
+			TraceExporters: []string{"jaeger://localhost:6831", "zap"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "trace-exporter test (multiple flags)",
+			Params:         []string{"--oc-trace-exporter", "jaeger://localhost:6831", "--oc-trace-exporter", "zap"},
+			TraceExporters: []string{"jaeger://localhost:6831", "zap"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:             "trace-id-generator test",
+			Params:           []string{"--oc-trace-id-generator=xray"},
+			TraceIDGenerator: "xray",
+			TraceSampler:     -1,
 		},
 		{
 			Name:         "trace-sampler test (1)",
@@ -78,10 +141,22 @@ func TestInitKingPin(t *testing.T) {
 			TraceSampler: 0.25,
 		},
 		{
-			Name:          "stats-exporter test",
-			Params:        []string{"--oc-stats-exporter", "prometheus://localhost:6831"},
-			StatsExporter: "prometheus://localhost:6831",
-			TraceSampler:  -1,
+			Name:           "stats-exporter test",
+			Params:         []string{"--oc-stats-exporter", "prometheus://localhost:6831"},
+			StatsExporters: []string{"prometheus://localhost:6831"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "stats-exporter test (comma separated)",
+			Params:         []string{"--oc-stats-exporter", "prometheus://localhost:6831,sd://my-project"},
+			StatsExporters: []string{"prometheus://localhost:6831", "sd://my-project"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "stats-exporter test (multiple flags)",
+			Params:         []string{"--oc-stats-exporter", "prometheus://localhost:6831", "--oc-stats-exporter", "sd://my-project"},
+			StatsExporters: []string{"prometheus://localhost:6831", "sd://my-project"},
+			TraceSampler:   -1,
 		},
 	}
 	for _, testcase := range testcases {
@@ -102,10 +177,20 @@ func TestInitKingPin(t *testing.T) {
 			assert.Equal(t, testcase.ServiceUrl, result.ServiceUrl)
 			assert.Equal(t, testcase.ConfigFile, result.ConfigFile)
 			assert.Equal(t, testcase.ZPage, result.ZPage)
+			assert.Equal(t, testcase.ListenPolicy, result.ListenPolicy)
+			assert.Equal(t, testcase.TLSCACert, result.TLSCACert)
+			assert.Equal(t, testcase.TLSClientCert, result.TLSClientCert)
+			assert.Equal(t, testcase.TLSClientKey, result.TLSClientKey)
+			assert.Equal(t, testcase.TLSInsecureSkipVerify, result.TLSInsecureSkipVerify)
+			assert.Equal(t, testcase.ExporterHeaders, result.ExporterHeaders)
+			assert.Equal(t, testcase.ExporterProxy, result.ExporterProxy)
 			assert.Equal(t, testcase.HoneycombKey, result.HoneycombKey)
-			assert.Equal(t, testcase.TraceExporter, result.TraceExporter)
+			assert.Equal(t, testcase.HoneycombDataset, result.HoneycombDataset)
+			assert.Equal(t, testcase.HoneycombAPIHost, result.HoneycombAPIHost)
+			assert.Equal(t, testcase.TraceExporters, result.TraceExporters)
+			assert.Equal(t, testcase.TraceIDGenerator, result.TraceIDGenerator)
 			assert.InDelta(t, testcase.TraceSampler, result.TraceSampler, 0.01)
-			assert.Equal(t, testcase.StatsExporter, result.StatsExporter)
+			assert.Equal(t, testcase.StatsExporters, result.StatsExporters)
 		})
 	}
 }
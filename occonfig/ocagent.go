@@ -0,0 +1,105 @@
+package occonfig
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"contrib.go.opencensus.io/exporter/ocagent"
+	"google.golang.org/grpc/credentials"
+	// registers gzip compressor for compression=gzip
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+// ocagentOptions builds options from ocagent://host:55678?tls=true&reconnectionPeriod=5s&headers=k=v&compression=gzip.
+// The connection is insecure (plaintext) unless tls is true or the scheme is ocagent+tls.
+// Config.ExporterHeaders are also sent as gRPC metadata. The headers query has higher priority.
+func ocagentOptions(exporter *Exporter, config *Config) ([]ocagent.ExporterOption, error) {
+	options := []ocagent.ExporterOption{
+		ocagent.WithAddress(exporter.Host),
+		ocagent.WithServiceName(config.ServiceName),
+	}
+	if exporter.Options.Get("tls") == "true" {
+		tlsConfig, err := newTLSConfig(exporter, config)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		options = append(options, ocagent.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		options = append(options, ocagent.WithInsecure())
+	}
+	if rawPeriod := exporter.Options.Get("reconnectionPeriod"); rawPeriod != "" {
+		period, err := time.ParseDuration(rawPeriod)
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("Invalid OpenCensus Agent reconnection period %q. It should be a positive duration like 5s", rawPeriod)
+		}
+		options = append(options, ocagent.WithReconnectionPeriod(period))
+	}
+	headers := make(map[string]string)
+	for key, value := range config.ExporterHeaders {
+		headers[key] = value
+	}
+	for _, rawHeaders := range exporter.Options["headers"] {
+		queryHeaders, err := parseHeaders(rawHeaders)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range queryHeaders {
+			headers[key] = value
+		}
+	}
+	if len(headers) > 0 {
+		options = append(options, ocagent.WithHeaders(headers))
+	}
+	switch compression := exporter.Options.Get("compression"); compression {
+	case "", "none":
+	case "gzip":
+		options = append(options, ocagent.UseCompressor(compression))
+	default:
+		return nil, fmt.Errorf("Invalid OpenCensus Agent compression %q. It should be 'gzip'|'none'", compression)
+	}
+	return options, nil
+}
+
+var ocagentFactory = &ExporterFactory{
+	Name: "OpenCensus Agent",
+	Mode: Trace | Stats,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		options := u.Query()
+		if u.Scheme == "ocagent+tls" {
+			options.Set("tls", "true")
+		}
+		host, port := hostPort(u, "localhost", "55678")
+		return &Exporter{
+			Type:    OCAGENT,
+			Host:    fmt.Sprintf("%s:%s", host, port),
+			Options: options,
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		options, err := ocagentOptions(exporter, config)
+		if err != nil {
+			return nil, err
+		}
+		oe, err := ocagent.NewExporter(options...)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create OpenCensus Agent exporter: %v", err)
+		}
+		return &ExporterInstance{
+			Trace:    oe,
+			Stats:    oe,
+			Finalize: oe.Stop,
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("ocagent", ocagentFactory)
+	RegisterExporterFactory("ocagent+tls", ocagentFactory)
+}
@@ -0,0 +1,197 @@
+package occonfig
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	agentmetricspb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/metrics/v1"
+	agenttracepb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// testAgent is a stand-in of OpenCensus Agent that records received spans, metrics and headers.
+type testAgent struct {
+	lock    sync.Mutex
+	spans   []string
+	metrics []string
+	tenants []string
+}
+
+func (a *testAgent) Config(stream agenttracepb.TraceService_ConfigServer) error {
+	<-stream.Context().Done()
+	return nil
+}
+
+func (a *testAgent) Export(stream agenttracepb.TraceService_ExportServer) error {
+	a.recordHeader(stream.Context())
+	for {
+		request, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		a.lock.Lock()
+		for _, span := range request.Spans {
+			a.spans = append(a.spans, span.Name.Value)
+		}
+		a.lock.Unlock()
+	}
+}
+
+func (a *testAgent) recordHeader(ctx context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	a.lock.Lock()
+	a.tenants = append(a.tenants, md.Get("x-tenant")...)
+	a.lock.Unlock()
+}
+
+type testMetricsAgent struct {
+	*testAgent
+}
+
+func (a testMetricsAgent) Export(stream agentmetricspb.MetricsService_ExportServer) error {
+	for {
+		request, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		a.lock.Lock()
+		for _, metric := range request.Metrics {
+			a.metrics = append(a.metrics, metric.MetricDescriptor.Name)
+		}
+		a.lock.Unlock()
+	}
+}
+
+func TestOCAgentOptions(t *testing.T) {
+	testcases := []struct {
+		Name   string
+		Source string
+		Count  int
+		Error  bool
+	}{
+		{
+			Name:   "default",
+			Source: "ocagent",
+			Count:  3,
+		},
+		{
+			Name:   "tls",
+			Source: "ocagent+tls://agent:55678",
+			Count:  3,
+		},
+		{
+			Name:   "all options",
+			Source: "ocagent://agent:55678?tls=true&insecureSkipVerify=true&reconnectionPeriod=5s&headers=X-Tenant=team-a&compression=gzip",
+			Count:  6,
+		},
+		{
+			Name:   "invalid reconnection period",
+			Source: "ocagent://agent:55678?reconnectionPeriod=5",
+			Error:  true,
+		},
+		{
+			Name:   "invalid headers",
+			Source: "ocagent://agent:55678?headers=X-Tenant",
+			Error:  true,
+		},
+		{
+			Name:   "invalid compression",
+			Source: "ocagent://agent:55678?compression=zstd",
+			Error:  true,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			exporter, err := SelectTraceExporter(testcase.Source)
+			assert.Nil(t, err)
+			options, err := ocagentOptions(exporter, &Config{ServiceName: "my-service"})
+			if testcase.Error {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.Count, len(options))
+		})
+	}
+}
+
+func TestOCAgentSharesInstance(t *testing.T) {
+	var finalizes []func() error
+	instances := newExporterInstances(context.Background(), &Config{ServiceName: "my-service"}, testLogger, &finalizes)
+	traceExporter, err := SelectTraceExporter("ocagent://127.0.0.1:1")
+	assert.Nil(t, err)
+	statsExporter, err := SelectStatsExporter("ocagent://127.0.0.1:1")
+	assert.Nil(t, err)
+	traceInstance, err := instances.get(traceExporter)
+	assert.Nil(t, err)
+	statsInstance, err := instances.get(statsExporter)
+	assert.Nil(t, err)
+	assert.True(t, traceInstance == statsInstance)
+	for _, finalize := range finalizes {
+		finalize()
+	}
+}
+
+func TestOCAgentExporter(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	agent := &testAgent{}
+	server := grpc.NewServer()
+	agenttracepb.RegisterTraceServiceServer(server, agent)
+	agentmetricspb.RegisterMetricsServiceServer(server, testMetricsAgent{agent})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	exporter, err := SelectTraceExporter("ocagent://" + listener.Addr().String() + "?compression=gzip&headers=X-Tenant=team-a")
+	assert.Nil(t, err)
+	instance, err := ocagentFactory.New(context.Background(), exporter, &Config{ServiceName: "my-service"}, testLogger)
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	measure := stats.Int64("ocagent_test_count", "count", stats.UnitDimensionless)
+	countView := &view.View{Name: "ocagent_test_count", Measure: measure, Aggregation: view.Count(), TagKeys: []tag.Key{}}
+	instance.Stats.ExportView(&view.Data{
+		View:  countView,
+		Start: time.Now(),
+		End:   time.Now(),
+		Rows:  []*view.Row{{Data: &view.CountData{Value: 1}}},
+	})
+	instance.Trace.ExportSpan(&trace.SpanData{
+		SpanContext: trace.SpanContext{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}},
+		Name:        "test-span",
+		StartTime:   time.Now(),
+		EndTime:     time.Now(),
+	})
+	// Stop closes the connection right after flushing. Flush and wait for the agent to receive data before that
+	instance.Trace.(interface{ Flush() }).Flush()
+	defer instance.Finalize()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		agent.lock.Lock()
+		received := len(agent.spans) > 0 && len(agent.metrics) > 0
+		agent.lock.Unlock()
+		if received {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	agent.lock.Lock()
+	defer agent.lock.Unlock()
+	assert.Equal(t, []string{"test-span"}, agent.spans)
+	assert.Equal(t, []string{"ocagent_test_count"}, agent.metrics)
+	assert.Contains(t, agent.tenants, "team-a")
+}
@@ -0,0 +1,78 @@
+package occonfig
+
+import (
+	"log"
+	"os"
+)
+
+// Option changes the behavior of InitWithConfig.
+type Option func(*initOptions)
+
+type initOptions struct {
+	mode               Mode
+	env                map[string]string
+	workingDir         string
+	withoutCommandLine bool
+	deferServerStart   bool
+	logger             *log.Logger
+}
+
+func newInitOptions(options []Option) *initOptions {
+	result := &initOptions{
+		mode:   Trace | Stats,
+		logger: log.New(os.Stderr, "", 0),
+	}
+	for _, option := range options {
+		option(result)
+	}
+	if result.env == nil {
+		result.env = envArrayToMap(os.Environ())
+	}
+	if result.workingDir == "" {
+		result.workingDir, _ = os.Getwd()
+	}
+	return result
+}
+
+// WithMode specifies the signals to initialize. Default value is Trace|Stats.
+func WithMode(mode Mode) Option {
+	return func(o *initOptions) {
+		o.mode = mode
+	}
+}
+
+// WithEnv uses env instead of the environment variables of the process.
+func WithEnv(env map[string]string) Option {
+	return func(o *initOptions) {
+		o.env = env
+	}
+}
+
+// WithWorkingDir specifies the folder that is used to resolve relative file paths in the config.
+// Default value is the current working directory.
+func WithWorkingDir(dir string) Option {
+	return func(o *initOptions) {
+		o.workingDir = dir
+	}
+}
+
+// WithoutCommandLine ignores the command line options registered by UseFlag or UseKingpin.
+func WithoutCommandLine() Option {
+	return func(o *initOptions) {
+		o.withoutCommandLine = true
+	}
+}
+
+// WithDeferredServerStart defers starting the HTTP servers of Prometheus and ZPage until OCConfig.StartServer() is called.
+func WithDeferredServerStart() Option {
+	return func(o *initOptions) {
+		o.deferServerStart = true
+	}
+}
+
+// WithLogger specifies the logger for occonfig's messages. Default logger writes to stderr.
+func WithLogger(logger *log.Logger) Option {
+	return func(o *initOptions) {
+		o.logger = logger
+	}
+}
@@ -0,0 +1,145 @@
+package occonfig
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// parseResourceAttributes parses OTEL_RESOURCE_ATTRIBUTES like "service.version=1.0,deployment.environment=prod".
+// Values can be percent encoded.
+func parseResourceAttributes(s string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, attribute := range splitExporters(s) {
+		i := strings.Index(attribute, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("Invalid OTEL_RESOURCE_ATTRIBUTES %q. It should be key=value", attribute)
+		}
+		value, err := url.PathUnescape(strings.TrimSpace(attribute[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid OTEL_RESOURCE_ATTRIBUTES %q: %v", attribute, err)
+		}
+		result[strings.TrimSpace(attribute[:i])] = value
+	}
+	return result, nil
+}
+
+// selectOTelSampler converts OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG into the sampling rate and Config.TraceSamplerParent.
+func selectOTelSampler(sampler, arg string) (float64, string, error) {
+	parent := SamplerParentIgnored
+	if strings.HasPrefix(sampler, "parentbased_") {
+		parent = SamplerParentBased
+	}
+	switch sampler {
+	case "":
+		return -1, "", nil
+	case "always_on", "parentbased_always_on":
+		return 1.0, parent, nil
+	case "always_off", "parentbased_always_off":
+		return 0.0, parent, nil
+	case "traceidratio", "parentbased_traceidratio":
+		if arg == "" {
+			return 1.0, parent, nil
+		}
+		ratio, err := strconv.ParseFloat(arg, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return 0, "", fmt.Errorf("Invalid OTEL_TRACES_SAMPLER_ARG %q. It should be a number between 0 and 1", arg)
+		}
+		return ratio, parent, nil
+	default:
+		return 0, "", fmt.Errorf("Invalid OTEL_TRACES_SAMPLER %q. It should be 'always_on'|'always_off'|'traceidratio'|'parentbased_always_on'|'parentbased_always_off'|'parentbased_traceidratio'", sampler)
+	}
+}
+
+// otlpExporterFromEnv converts OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_PROTOCOL into the exporter URL.
+func otlpExporterFromEnv(envMaps map[string]string) (string, error) {
+	scheme := "otlp+http"
+	query := url.Values{}
+	switch protocol := envMaps["OTEL_EXPORTER_OTLP_PROTOCOL"]; protocol {
+	case "", "http/protobuf":
+	case "http/json":
+		query.Set("encoding", "json")
+	case "grpc":
+		scheme = "otlp"
+	default:
+		return "", fmt.Errorf("Invalid OTEL_EXPORTER_OTLP_PROTOCOL %q. It should be 'grpc'|'http/protobuf'|'http/json'", protocol)
+	}
+	endpoint := envMaps["OTEL_EXPORTER_OTLP_ENDPOINT"]
+	host := ""
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return "", fmt.Errorf("Invalid OTEL_EXPORTER_OTLP_ENDPOINT %q. It should be a URL like http://collector:4318", endpoint)
+		}
+		if u.Scheme == "https" {
+			query.Set("tls", "true")
+		}
+		host = u.Host
+		if scheme != "otlp" {
+			host += strings.TrimSuffix(u.Path, "/")
+		}
+	}
+	if host == "" && len(query) == 0 {
+		return scheme, nil
+	}
+	result := scheme + "://" + host
+	if len(query) > 0 {
+		result += "?" + query.Encode()
+	}
+	return result, nil
+}
+
+// selectOTelTraceExporters converts OTEL_TRACES_EXPORTER into the exporter URLs. "none" disables exporters.
+func selectOTelTraceExporters(envMaps map[string]string) ([]string, error) {
+	var result []string
+	for _, name := range splitExporters(envMaps["OTEL_TRACES_EXPORTER"]) {
+		switch name {
+		case "otlp":
+			exporter, err := otlpExporterFromEnv(envMaps)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, exporter)
+		case "jaeger", "zipkin":
+			result = append(result, name)
+		case "console", "logging":
+			result = append(result, "zap")
+		case "none":
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("Invalid OTEL_TRACES_EXPORTER %q. It should be 'otlp'|'jaeger'|'zipkin'|'console'|'none'", name)
+		}
+	}
+	return result, nil
+}
+
+// initByOTelEnv reads the standard environment variables of OpenTelemetry. They have the lowest priority (lower than JSON files).
+func initByOTelEnv(envMaps map[string]string) (*Config, error) {
+	result := &Config{
+		TraceSampler: -1.0,
+	}
+	if rawAttributes, ok := envMaps["OTEL_RESOURCE_ATTRIBUTES"]; ok {
+		attributes, err := parseResourceAttributes(rawAttributes)
+		if err != nil {
+			return nil, err
+		}
+		result.ResourceAttributes = attributes
+		result.ServiceName = attributes["service.name"]
+	}
+	if serviceName, ok := envMaps["OTEL_SERVICE_NAME"]; ok && serviceName != "" {
+		result.ServiceName = serviceName
+	}
+	s, parent, err := selectOTelSampler(envMaps["OTEL_TRACES_SAMPLER"], envMaps["OTEL_TRACES_SAMPLER_ARG"])
+	if err != nil {
+		return nil, err
+	}
+	result.TraceSampler = s
+	result.TraceSamplerParent = parent
+	exporters, err := selectOTelTraceExporters(envMaps)
+	if err != nil {
+		return nil, err
+	}
+	result.TraceExporters = exporters
+	return result, nil
+}
@@ -0,0 +1,256 @@
+package occonfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOTelEnv(t *testing.T) {
+	testcases := []struct {
+		Name               string
+		Envs               []string
+		ServiceName        string
+		ResourceAttributes map[string]string
+		TraceExporters     []string
+		TraceSampler       float64
+		Error              bool
+	}{
+		{
+			Name:         "service name",
+			Envs:         []string{"OTEL_SERVICE_NAME=otel-service"},
+			ServiceName:  "otel-service",
+			TraceSampler: -1,
+		},
+		{
+			Name:               "resource attributes",
+			Envs:               []string{"OTEL_RESOURCE_ATTRIBUTES=service.name=attr-service, deployment.environment=prod,team=a%2Cb"},
+			ServiceName:        "attr-service",
+			ResourceAttributes: map[string]string{"service.name": "attr-service", "deployment.environment": "prod", "team": "a,b"},
+			TraceSampler:       -1,
+		},
+		{
+			Name:               "OTEL_SERVICE_NAME has higher priority than resource attributes",
+			Envs:               []string{"OTEL_RESOURCE_ATTRIBUTES=service.name=attr-service", "OTEL_SERVICE_NAME=otel-service"},
+			ServiceName:        "otel-service",
+			ResourceAttributes: map[string]string{"service.name": "attr-service"},
+			TraceSampler:       -1,
+		},
+		{
+			Name:  "invalid resource attributes",
+			Envs:  []string{"OTEL_RESOURCE_ATTRIBUTES=service.name"},
+			Error: true,
+		},
+		{
+			Name:         "always_on sampler",
+			Envs:         []string{"OTEL_TRACES_SAMPLER=always_on"},
+			TraceSampler: 1,
+		},
+		{
+			Name:         "parentbased_always_off sampler",
+			Envs:         []string{"OTEL_TRACES_SAMPLER=parentbased_always_off"},
+			TraceSampler: 0,
+		},
+		{
+			Name:         "traceidratio sampler",
+			Envs:         []string{"OTEL_TRACES_SAMPLER=parentbased_traceidratio", "OTEL_TRACES_SAMPLER_ARG=0.25"},
+			TraceSampler: 0.25,
+		},
+		{
+			Name:  "invalid sampler",
+			Envs:  []string{"OTEL_TRACES_SAMPLER=jaeger_remote"},
+			Error: true,
+		},
+		{
+			Name:  "invalid sampler arg",
+			Envs:  []string{"OTEL_TRACES_SAMPLER=traceidratio", "OTEL_TRACES_SAMPLER_ARG=2"},
+			Error: true,
+		},
+		{
+			Name:           "otlp exporter (default endpoint)",
+			Envs:           []string{"OTEL_TRACES_EXPORTER=otlp"},
+			TraceExporters: []string{"otlp+http"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "otlp exporter with endpoint",
+			Envs:           []string{"OTEL_TRACES_EXPORTER=otlp", "OTEL_EXPORTER_OTLP_ENDPOINT=https://collector:4318/otlp/"},
+			TraceExporters: []string{"otlp+http://collector:4318/otlp?tls=true"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "otlp exporter with grpc",
+			Envs:           []string{"OTEL_TRACES_EXPORTER=otlp,zipkin", "OTEL_EXPORTER_OTLP_ENDPOINT=http://collector:4317", "OTEL_EXPORTER_OTLP_PROTOCOL=grpc"},
+			TraceExporters: []string{"otlp://collector:4317", "zipkin"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:           "otlp exporter with json",
+			Envs:           []string{"OTEL_TRACES_EXPORTER=otlp", "OTEL_EXPORTER_OTLP_PROTOCOL=http/json"},
+			TraceExporters: []string{"otlp+http://?encoding=json"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:         "none exporter",
+			Envs:         []string{"OTEL_TRACES_EXPORTER=none"},
+			TraceSampler: -1,
+		},
+		{
+			Name:           "console exporter",
+			Envs:           []string{"OTEL_TRACES_EXPORTER=console"},
+			TraceExporters: []string{"zap"},
+			TraceSampler:   -1,
+		},
+		{
+			Name:  "invalid exporter",
+			Envs:  []string{"OTEL_TRACES_EXPORTER=newrelic"},
+			Error: true,
+		},
+		{
+			Name:  "invalid endpoint",
+			Envs:  []string{"OTEL_TRACES_EXPORTER=otlp", "OTEL_EXPORTER_OTLP_ENDPOINT=collector"},
+			Error: true,
+		},
+		{
+			Name:  "invalid protocol",
+			Envs:  []string{"OTEL_TRACES_EXPORTER=otlp", "OTEL_EXPORTER_OTLP_PROTOCOL=thrift"},
+			Error: true,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			result, err := initByOTelEnv(envArrayToMap(testcase.Envs))
+			if testcase.Error {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, testcase.ServiceName, result.ServiceName)
+			assert.Equal(t, testcase.ResourceAttributes, result.ResourceAttributes)
+			assert.Equal(t, testcase.TraceExporters, result.TraceExporters)
+			assert.Equal(t, testcase.TraceSampler, result.TraceSampler)
+		})
+	}
+}
+
+func TestSelectOTelSampler(t *testing.T) {
+	testcases := []struct {
+		Sampler  string
+		Arg      string
+		Fraction float64
+		Parent   string
+	}{
+		{"", "", -1, ""},
+		{"always_on", "", 1, SamplerParentIgnored},
+		{"always_off", "", 0, SamplerParentIgnored},
+		{"traceidratio", "0.25", 0.25, SamplerParentIgnored},
+		{"parentbased_always_on", "", 1, SamplerParentBased},
+		{"parentbased_always_off", "", 0, SamplerParentBased},
+		{"parentbased_traceidratio", "", 1, SamplerParentBased},
+		{"parentbased_traceidratio", "0", 0, SamplerParentBased},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Sampler+" "+testcase.Arg, func(t *testing.T) {
+			fraction, parent, err := selectOTelSampler(testcase.Sampler, testcase.Arg)
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.Fraction, fraction)
+			assert.Equal(t, testcase.Parent, parent)
+		})
+	}
+}
+
+func TestOTelEnvPriority(t *testing.T) {
+	testcases := []struct {
+		Name               string
+		Env                map[string]string
+		ServiceName        string
+		ResourceAttributes map[string]string
+		TraceExporters     []string
+		TraceSampler       float64
+		TraceSamplerParent string
+	}{
+		{
+			Name: "OC_* variables have higher priority",
+			Env: map[string]string{
+				"OTEL_SERVICE_NAME":    "otel-service",
+				"OTEL_TRACES_SAMPLER":  "always_on",
+				"OTEL_TRACES_EXPORTER": "otlp",
+				"OC_SERVICE_NAME":      "oc-service",
+				"OC_TRACE_SAMPLER":     "0.5",
+				"OC_TRACE_EXPORTER":    "jaeger",
+			},
+			ServiceName:    "oc-service",
+			TraceExporters: []string{"jaeger"},
+			TraceSampler:   0.5,
+		},
+		{
+			Name: "JSON file has higher priority",
+			Env: map[string]string{
+				"OTEL_SERVICE_NAME":        "otel-service",
+				"OTEL_RESOURCE_ATTRIBUTES": "team=a",
+				"OTEL_TRACES_SAMPLER":      "always_on",
+				"OTEL_TRACES_EXPORTER":     "otlp",
+				"OC_CONFIG_JSON":           "zap.json",
+			},
+			ServiceName:        "my-service-name-at-zap-json",
+			ResourceAttributes: map[string]string{"team": "a"},
+			TraceExporters:     []string{"zap"},
+			TraceSampler:       0,
+		},
+		{
+			Name: "OTEL_* variables fill empty settings",
+			Env: map[string]string{
+				"OTEL_SERVICE_NAME":    "otel-service",
+				"OTEL_TRACES_SAMPLER":  "always_off",
+				"OTEL_TRACES_EXPORTER": "zipkin",
+			},
+			ServiceName:        "otel-service",
+			TraceExporters:     []string{"zipkin"},
+			TraceSampler:       0,
+			TraceSamplerParent: SamplerParentIgnored,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			options := newInitOptions([]Option{
+				WithEnv(testcase.Env),
+				WithWorkingDir("testdata"),
+				WithoutCommandLine(),
+			})
+			config, err := getConfig(nil, options)
+			assert.Nil(t, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, testcase.ServiceName, config.ServiceName)
+			assert.Equal(t, testcase.ResourceAttributes, config.ResourceAttributes)
+			assert.Equal(t, testcase.TraceExporters, config.TraceExporters)
+			assert.Equal(t, testcase.TraceSampler, config.TraceSampler)
+			assert.Equal(t, testcase.TraceSamplerParent, config.TraceSamplerParent)
+		})
+	}
+}
+
+func TestOTelEnvExporterURL(t *testing.T) {
+	testcases := []struct {
+		Source string
+		Host   string
+	}{
+		{"otlp+http", "http://localhost:4318"},
+		{"otlp+http://?encoding=json", "http://localhost:4318"},
+		{"otlp+http://collector:4318/otlp?tls=true", "https://collector:4318/otlp"},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Source, func(t *testing.T) {
+			exporter, err := SelectTraceExporter(testcase.Source)
+			assert.Nil(t, err)
+			if exporter != nil {
+				assert.Equal(t, OTLP, exporter.Type)
+				assert.Equal(t, testcase.Host, exporter.Host)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package occonfig
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+	"google.golang.org/api/support/bundler"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+)
+
+// OTLP signals. They are the last element of the OTLP/HTTP paths (/v1/traces, /v1/metrics).
+const (
+	otlpTraces  = "traces"
+	otlpMetrics = "metrics"
+)
+
+// otlpGRPCMethods are the gRPC methods of the OTLP collector services.
+var otlpGRPCMethods = map[string]string{
+	otlpTraces:  "/opentelemetry.proto.collector.trace.v1.TraceService/Export",
+	otlpMetrics: "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export",
+}
+
+// otlpSender sends OTLP requests to the collector.
+type otlpSender interface {
+	send(ctx context.Context, signal string, request protoMessage) error
+	close() error
+}
+
+// otlpRawCodec passes encoded messages to gRPC as they are.
+type otlpRawCodec struct{}
+
+func (otlpRawCodec) Marshal(v interface{}) ([]byte, error) {
+	return v.([]byte), nil
+}
+
+func (otlpRawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = data
+	return nil
+}
+
+func (otlpRawCodec) Name() string {
+	return "proto"
+}
+
+type otlpGRPCSender struct {
+	conn        *grpc.ClientConn
+	headers     map[string]string
+	callOptions []grpc.CallOption
+}
+
+func (s *otlpGRPCSender) send(ctx context.Context, signal string, request protoMessage) error {
+	if len(s.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(s.headers))
+	}
+	var response []byte
+	return s.conn.Invoke(ctx, otlpGRPCMethods[signal], marshalProto(request), &response, s.callOptions...)
+}
+
+func (s *otlpGRPCSender) close() error {
+	return s.conn.Close()
+}
+
+type otlpHTTPSender struct {
+	client   *http.Client
+	endpoint string
+	headers  map[string]string
+	json     bool
+	gzip     bool
+}
+
+func (s *otlpHTTPSender) send(ctx context.Context, signal string, request protoMessage) error {
+	contentType := "application/x-protobuf"
+	body := marshalProto(request)
+	if s.json {
+		var err error
+		contentType = "application/json"
+		body, err = json.Marshal(request)
+		if err != nil {
+			return err
+		}
+	}
+	if s.gzip {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		writer.Write(body)
+		writer.Close()
+		body = compressed.Bytes()
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint+"/v1/"+signal, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+	if s.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	message, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d from %s: %s", res.StatusCode, req.URL, message)
+	}
+	return nil
+}
+
+func (s *otlpHTTPSender) close() error {
+	return nil
+}
+
+// otlpExporter converts spans and view data into OTLP and sends them in batches.
+type otlpExporter struct {
+	sender       otlpSender
+	logger       *log.Logger
+	resource     otlpResource
+	timeout      time.Duration
+	traceBundler *bundler.Bundler
+	viewBundler  *bundler.Bundler
+
+	lock sync.Mutex
+	err  error
+}
+
+func newOTLPExporter(sender otlpSender, config *Config, logger *log.Logger, timeout time.Duration, batchSize int, batchInterval time.Duration) *otlpExporter {
+	attributes := make(map[string]interface{})
+	for key, value := range config.ResourceAttributes {
+		attributes[key] = value
+	}
+	if config.ServiceName != "" {
+		attributes["service.name"] = config.ServiceName
+	}
+	e := &otlpExporter{
+		sender:   sender,
+		logger:   logger,
+		resource: otlpResource{Attributes: otlpAttributes(attributes)},
+		timeout:  timeout,
+	}
+	e.traceBundler = bundler.NewBundler((*trace.SpanData)(nil), func(bundle interface{}) {
+		e.upload(otlpTraces, newOTLPTraceRequest(e.resource, bundle.([]*trace.SpanData)))
+	})
+	e.viewBundler = bundler.NewBundler((*view.Data)(nil), func(bundle interface{}) {
+		e.upload(otlpMetrics, newOTLPMetricsRequest(e.resource, bundle.([]*view.Data)))
+	})
+	for _, b := range []*bundler.Bundler{e.traceBundler, e.viewBundler} {
+		b.BundleCountThreshold = batchSize
+		b.DelayThreshold = batchInterval
+	}
+	return e
+}
+
+func (e *otlpExporter) upload(signal string, request protoMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+	if err := e.sender.send(ctx, signal, request); err != nil {
+		err = fmt.Errorf("Failed to export %s to OTLP: %v", signal, err)
+		e.logger.Print(err)
+		e.lock.Lock()
+		e.err = err
+		e.lock.Unlock()
+	}
+}
+
+func (e *otlpExporter) ExportSpan(sd *trace.SpanData) {
+	if err := e.traceBundler.Add(sd, 1); err != nil {
+		e.logger.Printf("Failed to export span to OTLP: %v", err)
+	}
+}
+
+func (e *otlpExporter) ExportView(vd *view.Data) {
+	if err := e.viewBundler.Add(vd, 1); err != nil {
+		e.logger.Printf("Failed to export view data to OTLP: %v", err)
+	}
+}
+
+// Close sends the remaining data and closes the connection. It returns the last error while sending data.
+func (e *otlpExporter) Close() error {
+	e.traceBundler.Flush()
+	e.viewBundler.Flush()
+	closeErr := e.sender.close()
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.err != nil {
+		return e.err
+	}
+	return closeErr
+}
+
+// otlpHeaders returns Config.ExporterHeaders and headers query. The headers query has higher priority.
+func otlpHeaders(exporter *Exporter, config *Config, withConfigHeaders bool) (map[string]string, error) {
+	headers := make(map[string]string)
+	if withConfigHeaders {
+		for key, value := range config.ExporterHeaders {
+			headers[key] = value
+		}
+	}
+	for _, rawHeaders := range exporter.Options["headers"] {
+		queryHeaders, err := parseHeaders(rawHeaders)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range queryHeaders {
+			headers[key] = value
+		}
+	}
+	return headers, nil
+}
+
+// newOTLPSender creates the sender for otlp://host:4317 (gRPC) or otlp+http://host:4318 (HTTP).
+func newOTLPSender(exporter *Exporter, config *Config) (otlpSender, error) {
+	compression := exporter.Options.Get("compression")
+	if compression != "" && compression != "none" && compression != "gzip" {
+		return nil, fmt.Errorf("Invalid OTLP compression %q. It should be 'gzip'|'none'", compression)
+	}
+	encoding := exporter.Options.Get("encoding")
+	if encoding != "" && encoding != "proto" && encoding != "json" {
+		return nil, fmt.Errorf("Invalid OTLP encoding %q. It should be 'proto'|'json'", encoding)
+	}
+	if endpoint := httpEndpoint(exporter); strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		client, err := newHTTPClient(exporter, config)
+		if err != nil {
+			return nil, err
+		}
+		// newHTTPClient already adds Config.ExporterHeaders
+		headers, err := otlpHeaders(exporter, config, false)
+		if err != nil {
+			return nil, err
+		}
+		return &otlpHTTPSender{
+			client:   client,
+			endpoint: strings.TrimSuffix(endpoint, "/"),
+			headers:  headers,
+			json:     encoding == "json",
+			gzip:     compression == "gzip",
+		}, nil
+	}
+
+	if encoding == "json" {
+		return nil, fmt.Errorf("OTLP gRPC exporter doesn't support JSON encoding. Use otlp+http://")
+	}
+	headers, err := otlpHeaders(exporter, config, true)
+	if err != nil {
+		return nil, err
+	}
+	dialOptions := []grpc.DialOption{grpc.WithInsecure()}
+	if exporter.Options.Get("tls") == "true" {
+		tlsConfig, err := newTLSConfig(exporter, config)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		dialOptions = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	}
+	callOptions := []grpc.CallOption{grpc.ForceCodec(otlpRawCodec{})}
+	if compression == "gzip" {
+		callOptions = append(callOptions, grpc.UseCompressor("gzip"))
+	}
+	conn, err := grpc.Dial(exporter.Host, dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to OTLP collector %s: %v", exporter.Host, err)
+	}
+	return &otlpGRPCSender{
+		conn:        conn,
+		headers:     headers,
+		callOptions: callOptions,
+	}, nil
+}
+
+// otlpBatchOptions reads timeout (default 10s), batchSize (default 512) and batchInterval (default 5s).
+func otlpBatchOptions(exporter *Exporter) (time.Duration, int, time.Duration, error) {
+	duration := func(key string, defaultValue time.Duration) (time.Duration, error) {
+		rawValue := exporter.Options.Get(key)
+		if rawValue == "" {
+			return defaultValue, nil
+		}
+		value, err := time.ParseDuration(rawValue)
+		if err != nil || value <= 0 {
+			return 0, fmt.Errorf("Invalid OTLP %s %q. It should be a positive duration like 5s", key, rawValue)
+		}
+		return value, nil
+	}
+	timeout, err := duration("timeout", 10*time.Second)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	batchInterval, err := duration("batchInterval", 5*time.Second)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	batchSize := 512
+	if rawBatchSize := exporter.Options.Get("batchSize"); rawBatchSize != "" {
+		batchSize, err = strconv.Atoi(rawBatchSize)
+		if err != nil || batchSize <= 0 {
+			return 0, 0, 0, fmt.Errorf("Invalid OTLP batchSize %q. It should be a positive number", rawBatchSize)
+		}
+	}
+	return timeout, batchSize, batchInterval, nil
+}
+
+var otlpFactory = &ExporterFactory{
+	Name: "OTLP",
+	Mode: Trace | Stats,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		if u.Scheme == "otlp" {
+			host, port := hostPort(u, "localhost", "4317")
+			return &Exporter{
+				Type: OTLP,
+				Host: fmt.Sprintf("%s:%s", host, port),
+			}, nil
+		}
+		host, port := hostPort(u, "localhost", "4318")
+		return &Exporter{
+			Type: OTLP,
+			Host: fmt.Sprintf("%s://%s:%s%s", httpScheme(u), host, port, u.Path),
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		timeout, batchSize, batchInterval, err := otlpBatchOptions(exporter)
+		if err != nil {
+			return nil, err
+		}
+		sender, err := newOTLPSender(exporter, config)
+		if err != nil {
+			return nil, err
+		}
+		oe := newOTLPExporter(sender, config, logger, timeout, batchSize, batchInterval)
+		return &ExporterInstance{
+			Trace:    oe,
+			Stats:    oe,
+			Finalize: oe.Close,
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("otlp", otlpFactory)
+	RegisterExporterFactory("otlp+http", otlpFactory)
+	RegisterExporterFactory("otlp+https", otlpFactory)
+}
@@ -0,0 +1,592 @@
+package occonfig
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+)
+
+// OTLP messages that occonfig sends. The JSON tags follow the OTLP/JSON mapping
+// (lowerCamelCase names, hex encoded IDs and 64 bit integers as strings).
+
+// OTLP enum values.
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindServer   = 2
+	otlpSpanKindClient   = 3
+
+	otlpStatusCodeError = 2
+
+	otlpAggregationTemporalityCumulative = 2
+)
+
+// otlpID is a trace ID or a span ID. It is hex encoded in JSON.
+type otlpID []byte
+
+func (id otlpID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(id))
+}
+
+// otlpUint64s is repeated fixed64 field. The values are strings in JSON.
+type otlpUint64s []uint64
+
+func (values otlpUint64s) MarshalJSON() ([]byte, error) {
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = strconv.FormatUint(v, 10)
+	}
+	return json.Marshal(result)
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *int64   `json:"intValue,string,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (m *otlpAnyValue) marshalProto(p *protoBuffer) {
+	// oneof fields are written even if they are zero
+	switch {
+	case m.StringValue != nil:
+		p.tag(1, wireBytes)
+		p.varint(uint64(len(*m.StringValue)))
+		p.buf = append(p.buf, *m.StringValue...)
+	case m.BoolValue != nil:
+		p.tag(2, wireVarint)
+		if *m.BoolValue {
+			p.varint(1)
+		} else {
+			p.varint(0)
+		}
+	case m.IntValue != nil:
+		p.tag(3, wireVarint)
+		p.varint(uint64(*m.IntValue))
+	case m.DoubleValue != nil:
+		p.doubleField(4, *m.DoubleValue)
+	}
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+func (m *otlpKeyValue) marshalProto(p *protoBuffer) {
+	p.stringField(1, m.Key)
+	p.messageField(2, &m.Value)
+}
+
+func marshalKeyValues(p *protoBuffer, field int, attributes []otlpKeyValue) {
+	for i := range attributes {
+		p.messageField(field, &attributes[i])
+	}
+}
+
+// otlpAttributes converts attributes of OpenCensus. They are sorted by key.
+func otlpAttributes(attributes map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]otlpKeyValue, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, otlpKeyValue{Key: key, Value: otlpValue(attributes[key])})
+	}
+	return result
+}
+
+func otlpValue(value interface{}) otlpAnyValue {
+	switch v := value.(type) {
+	case string:
+		return otlpAnyValue{StringValue: &v}
+	case bool:
+		return otlpAnyValue{BoolValue: &v}
+	case int64:
+		return otlpAnyValue{IntValue: &v}
+	case int:
+		i := int64(v)
+		return otlpAnyValue{IntValue: &i}
+	case float64:
+		return otlpAnyValue{DoubleValue: &v}
+	default:
+		s := fmt.Sprint(v)
+		return otlpAnyValue{StringValue: &s}
+	}
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+func (m *otlpResource) marshalProto(p *protoBuffer) {
+	marshalKeyValues(p, 1, m.Attributes)
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+func (m *otlpScope) marshalProto(p *protoBuffer) {
+	p.stringField(1, m.Name)
+}
+
+// otlpInstrumentationScope is the scope of all data that occonfig sends.
+var otlpInstrumentationScope = otlpScope{Name: "github.com/future-architect/futureot/occonfig"}
+
+type otlpStatus struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
+
+func (m *otlpStatus) marshalProto(p *protoBuffer) {
+	p.stringField(2, m.Message)
+	p.uint64Field(3, uint64(m.Code))
+}
+
+type otlpEvent struct {
+	TimeUnixNano uint64         `json:"timeUnixNano,string"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+func (m *otlpEvent) marshalProto(p *protoBuffer) {
+	p.fixed64Field(1, m.TimeUnixNano)
+	p.stringField(2, m.Name)
+	marshalKeyValues(p, 3, m.Attributes)
+}
+
+type otlpLink struct {
+	TraceID    otlpID         `json:"traceId"`
+	SpanID     otlpID         `json:"spanId"`
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+func (m *otlpLink) marshalProto(p *protoBuffer) {
+	p.bytesField(1, m.TraceID)
+	p.bytesField(2, m.SpanID)
+	marshalKeyValues(p, 4, m.Attributes)
+}
+
+type otlpSpan struct {
+	TraceID                otlpID         `json:"traceId"`
+	SpanID                 otlpID         `json:"spanId"`
+	TraceState             string         `json:"traceState,omitempty"`
+	ParentSpanID           otlpID         `json:"parentSpanId,omitempty"`
+	Name                   string         `json:"name"`
+	Kind                   int            `json:"kind"`
+	StartTimeUnixNano      uint64         `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano        uint64         `json:"endTimeUnixNano,string"`
+	Attributes             []otlpKeyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int            `json:"droppedAttributesCount,omitempty"`
+	Events                 []otlpEvent    `json:"events,omitempty"`
+	DroppedEventsCount     int            `json:"droppedEventsCount,omitempty"`
+	Links                  []otlpLink     `json:"links,omitempty"`
+	DroppedLinksCount      int            `json:"droppedLinksCount,omitempty"`
+	Status                 otlpStatus     `json:"status"`
+}
+
+func (m *otlpSpan) marshalProto(p *protoBuffer) {
+	p.bytesField(1, m.TraceID)
+	p.bytesField(2, m.SpanID)
+	p.stringField(3, m.TraceState)
+	p.bytesField(4, m.ParentSpanID)
+	p.stringField(5, m.Name)
+	p.uint64Field(6, uint64(m.Kind))
+	p.fixed64Field(7, m.StartTimeUnixNano)
+	p.fixed64Field(8, m.EndTimeUnixNano)
+	marshalKeyValues(p, 9, m.Attributes)
+	p.uint64Field(10, uint64(m.DroppedAttributesCount))
+	for i := range m.Events {
+		p.messageField(11, &m.Events[i])
+	}
+	p.uint64Field(12, uint64(m.DroppedEventsCount))
+	for i := range m.Links {
+		p.messageField(13, &m.Links[i])
+	}
+	p.uint64Field(14, uint64(m.DroppedLinksCount))
+	p.messageField(15, &m.Status)
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+func (m *otlpScopeSpans) marshalProto(p *protoBuffer) {
+	p.messageField(1, &m.Scope)
+	for i := range m.Spans {
+		p.messageField(2, &m.Spans[i])
+	}
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+func (m *otlpResourceSpans) marshalProto(p *protoBuffer) {
+	p.messageField(1, &m.Resource)
+	for i := range m.ScopeSpans {
+		p.messageField(2, &m.ScopeSpans[i])
+	}
+}
+
+// otlpTraceRequest is ExportTraceServiceRequest.
+type otlpTraceRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func (m *otlpTraceRequest) marshalProto(p *protoBuffer) {
+	for i := range m.ResourceSpans {
+		p.messageField(1, &m.ResourceSpans[i])
+	}
+}
+
+func unixNano(t time.Time) uint64 {
+	if nano := t.UnixNano(); nano > 0 {
+		return uint64(nano)
+	}
+	return 0
+}
+
+// newOTLPSpan converts span data of OpenCensus like the OpenCensus bridge of OpenTelemetry.
+func newOTLPSpan(sd *trace.SpanData) otlpSpan {
+	span := otlpSpan{
+		TraceID:                otlpID(sd.TraceID[:]),
+		SpanID:                 otlpID(sd.SpanID[:]),
+		Name:                   sd.Name,
+		Kind:                   otlpSpanKindInternal,
+		StartTimeUnixNano:      unixNano(sd.StartTime),
+		EndTimeUnixNano:        unixNano(sd.EndTime),
+		Attributes:             otlpAttributes(sd.Attributes),
+		DroppedAttributesCount: sd.DroppedAttributeCount,
+		DroppedEventsCount:     sd.DroppedAnnotationCount + sd.DroppedMessageEventCount,
+		DroppedLinksCount:      sd.DroppedLinkCount,
+	}
+	if sd.ParentSpanID != (trace.SpanID{}) {
+		span.ParentSpanID = otlpID(sd.ParentSpanID[:])
+	}
+	switch sd.SpanKind {
+	case trace.SpanKindServer:
+		span.Kind = otlpSpanKindServer
+	case trace.SpanKindClient:
+		span.Kind = otlpSpanKindClient
+	}
+	if sd.Tracestate != nil {
+		var entries []string
+		for _, entry := range sd.Tracestate.Entries() {
+			entries = append(entries, entry.Key+"="+entry.Value)
+		}
+		span.TraceState = strings.Join(entries, ",")
+	}
+	// OK (0) of OpenCensus is unset in OTLP
+	if sd.Code != 0 {
+		span.Status = otlpStatus{Code: otlpStatusCodeError, Message: sd.Message}
+	}
+	for _, annotation := range sd.Annotations {
+		span.Events = append(span.Events, otlpEvent{
+			TimeUnixNano: unixNano(annotation.Time),
+			Name:         annotation.Message,
+			Attributes:   otlpAttributes(annotation.Attributes),
+		})
+	}
+	for _, event := range sd.MessageEvents {
+		eventType := "SENT"
+		if event.EventType == trace.MessageEventTypeRecv {
+			eventType = "RECEIVED"
+		}
+		span.Events = append(span.Events, otlpEvent{
+			TimeUnixNano: unixNano(event.Time),
+			Name:         "message",
+			Attributes: otlpAttributes(map[string]interface{}{
+				"message.type":              eventType,
+				"message.id":                event.MessageID,
+				"message.uncompressed_size": event.UncompressedByteSize,
+				"message.compressed_size":   event.CompressedByteSize,
+			}),
+		})
+	}
+	for _, link := range sd.Links {
+		span.Links = append(span.Links, otlpLink{
+			TraceID:    otlpID(link.TraceID[:]),
+			SpanID:     otlpID(link.SpanID[:]),
+			Attributes: otlpAttributes(link.Attributes),
+		})
+	}
+	return span
+}
+
+func newOTLPTraceRequest(resource otlpResource, data []*trace.SpanData) *otlpTraceRequest {
+	spans := make([]otlpSpan, 0, len(data))
+	for _, sd := range data {
+		spans = append(spans, newOTLPSpan(sd))
+	}
+	return &otlpTraceRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource:   resource,
+				ScopeSpans: []otlpScopeSpans{{Scope: otlpInstrumentationScope, Spans: spans}},
+			},
+		},
+	}
+}
+
+type otlpNumberDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64         `json:"startTimeUnixNano,string,omitempty"`
+	TimeUnixNano      uint64         `json:"timeUnixNano,string"`
+	AsDouble          *float64       `json:"asDouble,omitempty"`
+	AsInt             *int64         `json:"asInt,string,omitempty"`
+}
+
+func (m *otlpNumberDataPoint) marshalProto(p *protoBuffer) {
+	if m.StartTimeUnixNano != 0 {
+		p.fixed64Field(2, m.StartTimeUnixNano)
+	}
+	p.fixed64Field(3, m.TimeUnixNano)
+	if m.AsDouble != nil {
+		p.doubleField(4, *m.AsDouble)
+	}
+	if m.AsInt != nil {
+		p.fixed64Field(6, uint64(*m.AsInt))
+	}
+	marshalKeyValues(p, 7, m.Attributes)
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+func (m *otlpGauge) marshalProto(p *protoBuffer) {
+	for i := range m.DataPoints {
+		p.messageField(1, &m.DataPoints[i])
+	}
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+}
+
+func (m *otlpSum) marshalProto(p *protoBuffer) {
+	for i := range m.DataPoints {
+		p.messageField(1, &m.DataPoints[i])
+	}
+	p.uint64Field(2, uint64(m.AggregationTemporality))
+	p.boolField(3, m.IsMonotonic)
+}
+
+type otlpHistogramDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64         `json:"startTimeUnixNano,string"`
+	TimeUnixNano      uint64         `json:"timeUnixNano,string"`
+	Count             uint64         `json:"count,string"`
+	Sum               *float64       `json:"sum,omitempty"`
+	BucketCounts      otlpUint64s    `json:"bucketCounts"`
+	ExplicitBounds    []float64      `json:"explicitBounds"`
+	Min               *float64       `json:"min,omitempty"`
+	Max               *float64       `json:"max,omitempty"`
+}
+
+func (m *otlpHistogramDataPoint) marshalProto(p *protoBuffer) {
+	p.fixed64Field(2, m.StartTimeUnixNano)
+	p.fixed64Field(3, m.TimeUnixNano)
+	p.fixed64Field(4, m.Count)
+	if m.Sum != nil {
+		p.doubleField(5, *m.Sum)
+	}
+	p.packedFixed64Field(6, m.BucketCounts)
+	bounds := make([]uint64, len(m.ExplicitBounds))
+	for i, bound := range m.ExplicitBounds {
+		bounds[i] = math.Float64bits(bound)
+	}
+	p.packedFixed64Field(7, bounds)
+	marshalKeyValues(p, 9, m.Attributes)
+	if m.Min != nil {
+		p.doubleField(11, *m.Min)
+	}
+	if m.Max != nil {
+		p.doubleField(12, *m.Max)
+	}
+}
+
+type otlpHistogram struct {
+	DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                      `json:"aggregationTemporality"`
+}
+
+func (m *otlpHistogram) marshalProto(p *protoBuffer) {
+	for i := range m.DataPoints {
+		p.messageField(1, &m.DataPoints[i])
+	}
+	p.uint64Field(2, uint64(m.AggregationTemporality))
+}
+
+type otlpMetric struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Unit        string         `json:"unit,omitempty"`
+	Gauge       *otlpGauge     `json:"gauge,omitempty"`
+	Sum         *otlpSum       `json:"sum,omitempty"`
+	Histogram   *otlpHistogram `json:"histogram,omitempty"`
+}
+
+func (m *otlpMetric) marshalProto(p *protoBuffer) {
+	p.stringField(1, m.Name)
+	p.stringField(2, m.Description)
+	p.stringField(3, m.Unit)
+	switch {
+	case m.Gauge != nil:
+		p.messageField(5, m.Gauge)
+	case m.Sum != nil:
+		p.messageField(7, m.Sum)
+	case m.Histogram != nil:
+		p.messageField(9, m.Histogram)
+	}
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+func (m *otlpScopeMetrics) marshalProto(p *protoBuffer) {
+	p.messageField(1, &m.Scope)
+	for i := range m.Metrics {
+		p.messageField(2, &m.Metrics[i])
+	}
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+func (m *otlpResourceMetrics) marshalProto(p *protoBuffer) {
+	p.messageField(1, &m.Resource)
+	for i := range m.ScopeMetrics {
+		p.messageField(2, &m.ScopeMetrics[i])
+	}
+}
+
+// otlpMetricsRequest is ExportMetricsServiceRequest.
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+func (m *otlpMetricsRequest) marshalProto(p *protoBuffer) {
+	for i := range m.ResourceMetrics {
+		p.messageField(1, &m.ResourceMetrics[i])
+	}
+}
+
+// newOTLPMetric converts view data like the metric producer of OpenCensus:
+// count and sum are cumulative sums, distribution is a cumulative histogram and last value is a gauge.
+func newOTLPMetric(vd *view.Data) otlpMetric {
+	metric := otlpMetric{
+		Name:        vd.View.Name,
+		Description: vd.View.Description,
+		Unit:        vd.View.Measure.Unit(),
+	}
+	_, isInt := vd.View.Measure.(*stats.Int64Measure)
+	start := unixNano(vd.Start)
+	end := unixNano(vd.End)
+	numberPoint := func(row *view.Row, value float64, forceInt bool) otlpNumberDataPoint {
+		point := otlpNumberDataPoint{
+			Attributes:        otlpTags(row),
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+		}
+		if isInt || forceInt {
+			i := int64(value)
+			point.AsInt = &i
+		} else {
+			point.AsDouble = &value
+		}
+		return point
+	}
+	switch vd.View.Aggregation.Type {
+	case view.AggTypeCount, view.AggTypeSum:
+		metric.Sum = &otlpSum{AggregationTemporality: otlpAggregationTemporalityCumulative, IsMonotonic: true}
+		for _, row := range vd.Rows {
+			switch data := row.Data.(type) {
+			case *view.CountData:
+				metric.Sum.DataPoints = append(metric.Sum.DataPoints, numberPoint(row, float64(data.Value), true))
+			case *view.SumData:
+				metric.Sum.DataPoints = append(metric.Sum.DataPoints, numberPoint(row, data.Value, false))
+			}
+		}
+	case view.AggTypeLastValue:
+		metric.Gauge = &otlpGauge{}
+		for _, row := range vd.Rows {
+			if data, ok := row.Data.(*view.LastValueData); ok {
+				point := numberPoint(row, data.Value, false)
+				point.StartTimeUnixNano = 0
+				metric.Gauge.DataPoints = append(metric.Gauge.DataPoints, point)
+			}
+		}
+	case view.AggTypeDistribution:
+		metric.Histogram = &otlpHistogram{AggregationTemporality: otlpAggregationTemporalityCumulative}
+		for _, row := range vd.Rows {
+			data, ok := row.Data.(*view.DistributionData)
+			if !ok {
+				continue
+			}
+			sum := data.Mean * float64(data.Count)
+			point := otlpHistogramDataPoint{
+				Attributes:        otlpTags(row),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      end,
+				Count:             uint64(data.Count),
+				Sum:               &sum,
+				ExplicitBounds:    vd.View.Aggregation.Buckets,
+			}
+			for _, count := range data.CountPerBucket {
+				point.BucketCounts = append(point.BucketCounts, uint64(count))
+			}
+			if data.Count > 0 {
+				min, max := data.Min, data.Max
+				point.Min = &min
+				point.Max = &max
+			}
+			metric.Histogram.DataPoints = append(metric.Histogram.DataPoints, point)
+		}
+	}
+	return metric
+}
+
+func otlpTags(row *view.Row) []otlpKeyValue {
+	var result []otlpKeyValue
+	for _, t := range row.Tags {
+		value := t.Value
+		result = append(result, otlpKeyValue{Key: t.Key.Name(), Value: otlpAnyValue{StringValue: &value}})
+	}
+	return result
+}
+
+func newOTLPMetricsRequest(resource otlpResource, data []*view.Data) *otlpMetricsRequest {
+	metrics := make([]otlpMetric, 0, len(data))
+	for _, vd := range data {
+		metrics = append(metrics, newOTLPMetric(vd))
+	}
+	return &otlpMetricsRequest{
+		ResourceMetrics: []otlpResourceMetrics{
+			{
+				Resource:     resource,
+				ScopeMetrics: []otlpScopeMetrics{{Scope: otlpInstrumentationScope, Metrics: metrics}},
+			},
+		},
+	}
+}
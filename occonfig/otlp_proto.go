@@ -0,0 +1,101 @@
+package occonfig
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// protoBuffer writes protocol buffers wire format. OTLP messages are encoded by it
+// because the generated OTLP packages need newer gRPC than the other exporters.
+type protoBuffer struct {
+	buf []byte
+}
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func (p *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		p.buf = append(p.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	p.buf = append(p.buf, byte(v))
+}
+
+func (p *protoBuffer) tag(field int, wireType int) {
+	p.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+// uint64Field writes a varint field. Zero is omitted as proto3 does.
+func (p *protoBuffer) uint64Field(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	p.tag(field, wireVarint)
+	p.varint(v)
+}
+
+func (p *protoBuffer) boolField(field int, v bool) {
+	if v {
+		p.uint64Field(field, 1)
+	}
+}
+
+// fixed64Field writes a fixed64 field even if it is zero. It is used for oneof and optional fields too.
+func (p *protoBuffer) fixed64Field(field int, v uint64) {
+	p.tag(field, wireFixed64)
+	p.buf = append(p.buf, make([]byte, 8)...)
+	binary.LittleEndian.PutUint64(p.buf[len(p.buf)-8:], v)
+}
+
+func (p *protoBuffer) doubleField(field int, v float64) {
+	p.fixed64Field(field, math.Float64bits(v))
+}
+
+func (p *protoBuffer) bytesField(field int, v []byte) {
+	if len(v) == 0 {
+		return
+	}
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(v)))
+	p.buf = append(p.buf, v...)
+}
+
+func (p *protoBuffer) stringField(field int, v string) {
+	p.bytesField(field, []byte(v))
+}
+
+// packedFixed64Field writes repeated fixed64 or double values.
+func (p *protoBuffer) packedFixed64Field(field int, values []uint64) {
+	if len(values) == 0 {
+		return
+	}
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(values) * 8))
+	for _, v := range values {
+		p.buf = append(p.buf, make([]byte, 8)...)
+		binary.LittleEndian.PutUint64(p.buf[len(p.buf)-8:], v)
+	}
+}
+
+// messageField writes an embedded message. An empty message is written too because it may be a oneof field.
+func (p *protoBuffer) messageField(field int, message protoMessage) {
+	var child protoBuffer
+	message.marshalProto(&child)
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(child.buf)))
+	p.buf = append(p.buf, child.buf...)
+}
+
+type protoMessage interface {
+	marshalProto(p *protoBuffer)
+}
+
+func marshalProto(message protoMessage) []byte {
+	var p protoBuffer
+	message.marshalProto(&p)
+	return p.buf
+}
@@ -0,0 +1,384 @@
+package occonfig
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestOTLPOptions(t *testing.T) {
+	testcases := []struct {
+		Name   string
+		Source string
+		Error  bool
+	}{
+		{
+			Name:   "grpc",
+			Source: "otlp://127.0.0.1:1",
+		},
+		{
+			Name:   "grpc with all options",
+			Source: "otlp://127.0.0.1:1?tls=true&insecureSkipVerify=true&headers=X-Tenant=team-a&compression=gzip&timeout=3s&batchSize=100&batchInterval=1s",
+		},
+		{
+			Name:   "http with all options",
+			Source: "otlp+http://127.0.0.1:1?headers=X-Tenant=team-a&compression=gzip&encoding=json&timeout=3s&batchSize=100&batchInterval=1s",
+		},
+		{
+			Name:   "json encoding of grpc",
+			Source: "otlp://127.0.0.1:1?encoding=json",
+			Error:  true,
+		},
+		{
+			Name:   "invalid encoding",
+			Source: "otlp+http://127.0.0.1:1?encoding=xml",
+			Error:  true,
+		},
+		{
+			Name:   "invalid compression",
+			Source: "otlp://127.0.0.1:1?compression=zstd",
+			Error:  true,
+		},
+		{
+			Name:   "invalid headers",
+			Source: "otlp://127.0.0.1:1?headers=X-Tenant",
+			Error:  true,
+		},
+		{
+			Name:   "invalid timeout",
+			Source: "otlp://127.0.0.1:1?timeout=10",
+			Error:  true,
+		},
+		{
+			Name:   "invalid batch size",
+			Source: "otlp://127.0.0.1:1?batchSize=0",
+			Error:  true,
+		},
+		{
+			Name:   "invalid batch interval",
+			Source: "otlp://127.0.0.1:1?batchInterval=-1s",
+			Error:  true,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			exporter, err := SelectTraceExporter(testcase.Source)
+			assert.Nil(t, err)
+			instance, err := otlpFactory.New(context.Background(), exporter, &Config{ServiceName: "my-service"}, testLogger)
+			if testcase.Error {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			if instance != nil {
+				assert.Nil(t, instance.Finalize())
+			}
+		})
+	}
+}
+
+func TestOTLPSharesInstance(t *testing.T) {
+	var finalizes []func() error
+	instances := newExporterInstances(context.Background(), &Config{ServiceName: "my-service"}, testLogger, &finalizes)
+	traceExporter, err := SelectTraceExporter("otlp+http://127.0.0.1:1")
+	assert.Nil(t, err)
+	statsExporter, err := SelectStatsExporter("otlp+http://127.0.0.1:1")
+	assert.Nil(t, err)
+	traceInstance, err := instances.get(traceExporter)
+	assert.Nil(t, err)
+	statsInstance, err := instances.get(statsExporter)
+	assert.Nil(t, err)
+	assert.True(t, traceInstance == statsInstance)
+	for _, finalize := range finalizes {
+		finalize()
+	}
+}
+
+func exportTestView(t *testing.T, instance *ExporterInstance) {
+	measure := stats.Int64("otlp_test_count", "count", stats.UnitDimensionless)
+	countView := &view.View{Name: "otlp_test_count", Measure: measure, Aggregation: view.Count(), TagKeys: []tag.Key{}}
+	instance.Stats.ExportView(&view.Data{
+		View:  countView,
+		Start: time.Now(),
+		End:   time.Now(),
+		Rows:  []*view.Row{{Data: &view.CountData{Value: 1}}},
+	})
+}
+
+func TestOTLPHTTPExporter(t *testing.T) {
+	testcases := []struct {
+		Name        string
+		Query       string
+		ContentType string
+		Gzip        bool
+	}{
+		{
+			Name:        "proto",
+			Query:       "?headers=X-Tenant=team-a",
+			ContentType: "application/x-protobuf",
+		},
+		{
+			Name:        "json with gzip",
+			Query:       "?headers=X-Tenant=team-a&encoding=json&compression=gzip",
+			ContentType: "application/json",
+			Gzip:        true,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			var lock sync.Mutex
+			bodies := make(map[string][]byte)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, testcase.ContentType, r.Header.Get("Content-Type"))
+				assert.Equal(t, "team-a", r.Header.Get("X-Tenant"))
+				var reader = r.Body
+				if testcase.Gzip {
+					assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+					gzipReader, err := gzip.NewReader(r.Body)
+					assert.Nil(t, err)
+					reader = gzipReader
+				}
+				body, _ := ioutil.ReadAll(reader)
+				lock.Lock()
+				bodies[r.URL.Path] = body
+				lock.Unlock()
+			}))
+			defer server.Close()
+
+			exporter, err := SelectTraceExporter(strings.Replace(server.URL, "http://", "otlp+http://", 1) + "/otlp" + testcase.Query)
+			assert.Nil(t, err)
+			instance, err := otlpFactory.New(context.Background(), exporter, &Config{ServiceName: "my-service"}, testLogger)
+			assert.Nil(t, err)
+			if err != nil {
+				return
+			}
+			exportTestView(t, instance)
+			exportTestSpan(t, instance)
+
+			lock.Lock()
+			defer lock.Unlock()
+			traces := bodies["/otlp/v1/traces"]
+			metrics := bodies["/otlp/v1/metrics"]
+			assert.Contains(t, string(traces), "test-span")
+			assert.Contains(t, string(traces), "my-service")
+			assert.Contains(t, string(metrics), "otlp_test_count")
+			if testcase.ContentType == "application/json" {
+				var request struct {
+					ResourceSpans []struct {
+						ScopeSpans []struct {
+							Spans []struct {
+								TraceID string `json:"traceId"`
+								SpanID  string `json:"spanId"`
+								Name    string `json:"name"`
+							} `json:"spans"`
+						} `json:"scopeSpans"`
+					} `json:"resourceSpans"`
+				}
+				assert.Nil(t, json.Unmarshal(traces, &request))
+				span := request.ResourceSpans[0].ScopeSpans[0].Spans[0]
+				assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", span.TraceID)
+				assert.Equal(t, "0102030405060708", span.SpanID)
+				assert.Equal(t, "test-span", span.Name)
+			} else {
+				assert.True(t, bytes.Contains(traces, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}))
+			}
+		})
+	}
+}
+
+func TestOTLPHTTPExporterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	exporter, err := SelectTraceExporter(strings.Replace(server.URL, "http://", "otlp+http://", 1))
+	assert.Nil(t, err)
+	instance, err := otlpFactory.New(context.Background(), exporter, &Config{ServiceName: "my-service"}, testLogger)
+	assert.Nil(t, err)
+	exportTestView(t, instance)
+	assert.NotNil(t, instance.Finalize())
+}
+
+// testOTLPCodec is the server side codec of the OTLP gRPC test server that keeps raw messages.
+type testOTLPCodec struct {
+	otlpRawCodec
+}
+
+func (testOTLPCodec) String() string {
+	return "proto"
+}
+
+func TestOTLPGRPCExporter(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	var lock sync.Mutex
+	bodies := make(map[string][]byte)
+	var tenants []string
+	server := grpc.NewServer(grpc.CustomCodec(testOTLPCodec{}), grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		var body []byte
+		if err := stream.RecvMsg(&body); err != nil {
+			return err
+		}
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		lock.Lock()
+		bodies[method] = body
+		tenants = append(tenants, md.Get("x-tenant")...)
+		lock.Unlock()
+		return stream.SendMsg([]byte{})
+	}))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	exporter, err := SelectTraceExporter("otlp://" + listener.Addr().String() + "?compression=gzip&headers=X-Tenant=team-a")
+	assert.Nil(t, err)
+	instance, err := otlpFactory.New(context.Background(), exporter, &Config{ServiceName: "my-service"}, testLogger)
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	exportTestView(t, instance)
+	exportTestSpan(t, instance)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Contains(t, string(bodies["/opentelemetry.proto.collector.trace.v1.TraceService/Export"]), "test-span")
+	assert.Contains(t, string(bodies["/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"]), "otlp_test_count")
+	assert.Equal(t, []string{"team-a", "team-a"}, tenants)
+}
+
+func TestOTLPResourceAttributes(t *testing.T) {
+	config := &Config{ServiceName: "my-service", ResourceAttributes: map[string]string{"service.name": "other", "team": "a"}}
+	exporter := newOTLPExporter(&otlpHTTPSender{}, config, testLogger, 0, 1, 0)
+	assert.Equal(t, 2, len(exporter.resource.Attributes))
+	assert.Equal(t, "service.name", exporter.resource.Attributes[0].Key)
+	assert.Equal(t, "my-service", *exporter.resource.Attributes[0].Value.StringValue)
+	assert.Equal(t, "team", exporter.resource.Attributes[1].Key)
+}
+
+func TestMarshalProto(t *testing.T) {
+	value := "v"
+	message := &otlpEvent{
+		TimeUnixNano: 1,
+		Name:         "e",
+		Attributes:   []otlpKeyValue{{Key: "k", Value: otlpAnyValue{StringValue: &value}}},
+	}
+	assert.Equal(t, []byte{
+		0x09, 1, 0, 0, 0, 0, 0, 0, 0, // time_unix_nano (fixed64)
+		0x12, 1, 'e', // name
+		0x1a, 8, 0x0a, 1, 'k', 0x12, 3, 0x0a, 1, 'v', // attributes
+	}, marshalProto(message))
+}
+
+func TestNewOTLPSpan(t *testing.T) {
+	start := time.Unix(1, 0)
+	span := newOTLPSpan(&trace.SpanData{
+		SpanContext:  trace.SpanContext{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}},
+		ParentSpanID: trace.SpanID{3},
+		SpanKind:     trace.SpanKindServer,
+		Name:         "test-span",
+		StartTime:    start,
+		EndTime:      start.Add(time.Second),
+		Attributes:   map[string]interface{}{"b": int64(1), "a": "value"},
+		Annotations:  []trace.Annotation{{Time: start, Message: "annotation"}},
+		MessageEvents: []trace.MessageEvent{
+			{Time: start, EventType: trace.MessageEventTypeRecv, MessageID: 1, UncompressedByteSize: 10},
+		},
+		Links:  []trace.Link{{TraceID: trace.TraceID{4}, SpanID: trace.SpanID{5}}},
+		Status: trace.Status{Code: trace.StatusCodeInternal, Message: "failed"},
+	})
+	assert.Equal(t, otlpID{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, span.TraceID)
+	assert.Equal(t, otlpID{3, 0, 0, 0, 0, 0, 0, 0}, span.ParentSpanID)
+	assert.Equal(t, otlpSpanKindServer, span.Kind)
+	assert.Equal(t, uint64(1000000000), span.StartTimeUnixNano)
+	assert.Equal(t, uint64(2000000000), span.EndTimeUnixNano)
+	assert.Equal(t, "a", span.Attributes[0].Key)
+	assert.Equal(t, int64(1), *span.Attributes[1].Value.IntValue)
+	assert.Equal(t, 2, len(span.Events))
+	assert.Equal(t, "annotation", span.Events[0].Name)
+	assert.Equal(t, "message", span.Events[1].Name)
+	assert.Equal(t, 1, len(span.Links))
+	assert.Equal(t, otlpStatus{Code: otlpStatusCodeError, Message: "failed"}, span.Status)
+}
+
+func TestNewOTLPMetric(t *testing.T) {
+	floatMeasure := stats.Float64("otlp_test_latency", "latency", stats.UnitMilliseconds)
+	key, _ := tag.NewKey("method")
+	start := time.Unix(1, 0)
+	end := time.Unix(2, 0)
+	t.Run("distribution", func(t *testing.T) {
+		metric := newOTLPMetric(&view.Data{
+			View:  &view.View{Name: "latency", Measure: floatMeasure, Aggregation: view.Distribution(10, 100), TagKeys: []tag.Key{key}},
+			Start: start,
+			End:   end,
+			Rows: []*view.Row{{
+				Tags: []tag.Tag{{Key: key, Value: "GET"}},
+				Data: &view.DistributionData{Count: 2, Min: 5, Max: 50, Mean: 27.5, CountPerBucket: []int64{1, 1, 0}},
+			}},
+		})
+		assert.Equal(t, "ms", metric.Unit)
+		assert.NotNil(t, metric.Histogram)
+		if metric.Histogram == nil {
+			return
+		}
+		point := metric.Histogram.DataPoints[0]
+		assert.Equal(t, uint64(2), point.Count)
+		assert.Equal(t, 55.0, *point.Sum)
+		assert.Equal(t, otlpUint64s{1, 1, 0}, point.BucketCounts)
+		assert.Equal(t, []float64{10, 100}, point.ExplicitBounds)
+		assert.Equal(t, 5.0, *point.Min)
+		assert.Equal(t, 50.0, *point.Max)
+		assert.Equal(t, "GET", *point.Attributes[0].Value.StringValue)
+	})
+	t.Run("last value", func(t *testing.T) {
+		metric := newOTLPMetric(&view.Data{
+			View:  &view.View{Name: "latest", Measure: floatMeasure, Aggregation: view.LastValue()},
+			Start: start,
+			End:   end,
+			Rows:  []*view.Row{{Data: &view.LastValueData{Value: 1.5}}},
+		})
+		assert.NotNil(t, metric.Gauge)
+		if metric.Gauge == nil {
+			return
+		}
+		point := metric.Gauge.DataPoints[0]
+		assert.Equal(t, uint64(0), point.StartTimeUnixNano)
+		assert.Equal(t, 1.5, *point.AsDouble)
+	})
+	t.Run("count", func(t *testing.T) {
+		metric := newOTLPMetric(&view.Data{
+			View:  &view.View{Name: "count", Measure: floatMeasure, Aggregation: view.Count()},
+			Start: start,
+			End:   end,
+			Rows:  []*view.Row{{Data: &view.CountData{Value: 3}}},
+		})
+		assert.NotNil(t, metric.Sum)
+		if metric.Sum == nil {
+			return
+		}
+		assert.True(t, metric.Sum.IsMonotonic)
+		assert.Equal(t, int64(3), *metric.Sum.DataPoints[0].AsInt)
+	})
+}
@@ -0,0 +1,149 @@
+package occonfig
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+
+	"contrib.go.opencensus.io/exporter/prometheus"
+)
+
+// prometheusOptions builds options from prometheus://host:port/path?constLabels=env=prod,team=a.
+func prometheusOptions(exporter *Exporter, config *Config) (prometheus.Options, error) {
+	options := prometheus.Options{
+		Namespace: config.ServiceName,
+	}
+	for _, labels := range exporter.Options["constLabels"] {
+		constLabels, err := parseLabels("Prometheus const label", labels)
+		if err != nil {
+			return prometheus.Options{}, err
+		}
+		if options.ConstLabels == nil {
+			options.ConstLabels = make(map[string]string)
+		}
+		for key, value := range constLabels {
+			options.ConstLabels[key] = value
+		}
+	}
+	return options, nil
+}
+
+// prometheusEndpoint returns the URL that the handler is served at.
+func prometheusEndpoint(exporter *Exporter) (*url.URL, error) {
+	endpoint, err := url.Parse(httpEndpoint(exporter))
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Path == "" {
+		endpoint.Path = "/metrics"
+	}
+	return endpoint, nil
+}
+
+// prometheusTLSConfig loads the server certificate (certFile, keyFile) for https endpoint.
+func prometheusTLSConfig(exporter *Exporter, endpoint *url.URL) (*tls.Config, error) {
+	certFile := exporter.Options.Get("certFile")
+	keyFile := exporter.Options.Get("keyFile")
+	if endpoint.Scheme != "https" {
+		if certFile != "" || keyFile != "" {
+			return nil, errors.New("Prometheus certFile and keyFile need https endpoint (prometheus+https:// or tls=true)")
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("Both certFile and keyFile are needed for Prometheus https endpoint")
+	}
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load Prometheus server certificate: %v", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+	}, nil
+}
+
+// basicAuthHandler requires username and password to access handler.
+func basicAuthHandler(username, password string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(u), []byte(username)) != 1 || subtle.ConstantTimeCompare([]byte(p), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+var prometheusFactory = &ExporterFactory{
+	Name: "Prometheus",
+	Mode: Stats,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		options := u.Query()
+		if u.User != nil {
+			options.Set("username", u.User.Username())
+			if password, ok := u.User.Password(); ok {
+				options.Set("password", password)
+			}
+		}
+		if u.Hostname() == "embedded" {
+			return &Exporter{
+				Type:    PROMETHEUS,
+				Host:    "embedded:" + u.Path,
+				Options: options,
+			}, nil
+		}
+		port := u.Port()
+		if port == "" {
+			port = "8888"
+		}
+		return &Exporter{
+			Type:    PROMETHEUS,
+			Host:    fmt.Sprintf("%s://%s%s", httpScheme(u), net.JoinHostPort(u.Hostname(), port), u.Path),
+			Options: options,
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		options, err := prometheusOptions(exporter, config)
+		if err != nil {
+			return nil, err
+		}
+		endpoint, err := prometheusEndpoint(exporter)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig, err := prometheusTLSConfig(exporter, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		pe, err := prometheus.NewExporter(options)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create Prometheus exporter: %v", err)
+		}
+		var handler http.Handler = pe
+		username := exporter.Options.Get("username")
+		password := exporter.Options.Get("password")
+		if username != "" || password != "" {
+			handler = basicAuthHandler(username, password, pe)
+		}
+		return &ExporterInstance{
+			Stats:     pe,
+			Handler:   handler,
+			Endpoint:  endpoint,
+			TLSConfig: tlsConfig,
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("prometheus", prometheusFactory)
+	RegisterExporterFactory("p8s", prometheusFactory)
+	RegisterExporterFactory("prometheus+https", prometheusFactory)
+	RegisterExporterFactory("p8s+https", prometheusFactory)
+}
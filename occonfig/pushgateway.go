@@ -0,0 +1,170 @@
+package occonfig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"contrib.go.opencensus.io/exporter/prometheus"
+	prometheusclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// pushgatewayInterval returns the push interval in interval query (e.g. 10s). The default is 15 seconds.
+func pushgatewayInterval(exporter *Exporter) (time.Duration, error) {
+	rawInterval := exporter.Options.Get("interval")
+	if rawInterval == "" {
+		return 15 * time.Second, nil
+	}
+	interval, err := time.ParseDuration(rawInterval)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("Invalid Pushgateway interval %q. It should be a positive duration like 10s", rawInterval)
+	}
+	return interval, nil
+}
+
+// newPusher builds the pusher from pushgateway://host:port/job?grouping=instance=host1&timeout=10s.
+// The job defaults to the service name.
+func newPusher(exporter *Exporter, config *Config, registry *prometheusclient.Registry) (*push.Pusher, error) {
+	job := exporter.Options.Get("job")
+	if job == "" {
+		job = config.ServiceName
+	}
+	if strings.Contains(job, "/") {
+		return nil, fmt.Errorf("Invalid Pushgateway job %q. It should not contain '/'", job)
+	}
+	client, err := newHTTPClient(exporter, config)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = 10 * time.Second
+	if rawTimeout := exporter.Options.Get("timeout"); rawTimeout != "" {
+		timeout, err := time.ParseDuration(rawTimeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("Invalid Pushgateway timeout %q. It should be a positive duration like 10s", rawTimeout)
+		}
+		client.Timeout = timeout
+	}
+	pusher := push.New(httpEndpoint(exporter), job).Gatherer(registry).Client(client)
+	for _, rawGrouping := range exporter.Options["grouping"] {
+		grouping, err := parseLabels("Pushgateway grouping label", rawGrouping)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range grouping {
+			pusher.Grouping(name, value)
+		}
+	}
+	username := exporter.Options.Get("username")
+	password := exporter.Options.Get("password")
+	if username != "" || password != "" {
+		pusher.BasicAuth(username, password)
+	}
+	return pusher, nil
+}
+
+// intervalPusher pushes metrics at the interval until it is closed.
+type intervalPusher struct {
+	pusher *push.Pusher
+	logger *log.Logger
+	lock   sync.Mutex
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func newIntervalPusher(pusher *push.Pusher, interval time.Duration, logger *log.Logger) *intervalPusher {
+	p := &intervalPusher{
+		pusher: pusher,
+		logger: logger,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		defer close(p.done)
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.push(); err != nil {
+					p.logger.Printf("Failed to push metrics to Pushgateway: %v", err)
+				}
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func (p *intervalPusher) push() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.pusher.Push()
+}
+
+// Close stops the timer and pushes the metrics at last.
+func (p *intervalPusher) Close() error {
+	close(p.stop)
+	<-p.done
+	if err := p.push(); err != nil {
+		return fmt.Errorf("Failed to push metrics to Pushgateway: %v", err)
+	}
+	return nil
+}
+
+var pushgatewayFactory = &ExporterFactory{
+	Name: "Pushgateway",
+	Mode: Stats,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		options := u.Query()
+		if u.User != nil {
+			options.Set("username", u.User.Username())
+			if password, ok := u.User.Password(); ok {
+				options.Set("password", password)
+			}
+		}
+		if job := strings.Trim(u.Path, "/"); job != "" {
+			options.Set("job", job)
+		}
+		host, port := hostPort(u, "localhost", "9091")
+		return &Exporter{
+			Type:    PUSHGATEWAY,
+			Host:    fmt.Sprintf("%s://%s:%s", httpScheme(u), host, port),
+			Options: options,
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		options, err := prometheusOptions(exporter, config)
+		if err != nil {
+			return nil, err
+		}
+		interval, err := pushgatewayInterval(exporter)
+		if err != nil {
+			return nil, err
+		}
+		options.Registry = prometheusclient.NewRegistry()
+		pusher, err := newPusher(exporter, config, options.Registry)
+		if err != nil {
+			return nil, err
+		}
+		pe, err := prometheus.NewExporter(options)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create Pushgateway exporter: %v", err)
+		}
+		ip := newIntervalPusher(pusher, interval, logger)
+		return &ExporterInstance{
+			Stats:    pe,
+			Finalize: ip.Close,
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("pushgateway", pushgatewayFactory)
+	RegisterExporterFactory("pushgateway+https", pushgatewayFactory)
+}
@@ -0,0 +1,197 @@
+package occonfig
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+)
+
+// ExporterInstance is an exporter that is created by ExporterFactory.
+type ExporterInstance struct {
+	// Trace is registered via trace.RegisterExporter when the instance is used as a trace exporter. Init fails if it is nil then.
+	Trace trace.Exporter
+	// Stats is registered via view.RegisterExporter when the instance is used as a stats exporter. Init fails if it is nil then.
+	Stats view.Exporter
+	// Handler is served at Endpoint (e.g. http://:8888/metrics) if it is not nil. Endpoint is required with Handler.
+	Handler  http.Handler
+	Endpoint *url.URL
+	// TLSConfig has the server certificate if Endpoint is https.
+	TLSConfig *tls.Config
+	// Finalize is called once when OCConfig is closed. It should flush the exporter.
+	Finalize func() error
+
+	name string
+}
+
+// validate checks that the instance has the exporter for the signal. A nil exporter panics when data is sent.
+func (i *ExporterInstance) validate(mode Mode) error {
+	if mode&Trace == Trace && i.Trace == nil {
+		return fmt.Errorf("%s exporter factory doesn't return the trace exporter", i.name)
+	}
+	if mode&Stats == Stats && i.Stats == nil {
+		return fmt.Errorf("%s exporter factory doesn't return the stats exporter", i.name)
+	}
+	if i.Handler != nil && i.Endpoint == nil {
+		return fmt.Errorf("%s exporter factory returns the handler without the endpoint", i.name)
+	}
+	return nil
+}
+
+// ExporterFactory creates exporters for the URL schemes that are registered by RegisterExporterFactory.
+type ExporterFactory struct {
+	// Name is a human readable exporter name used in messages.
+	Name string
+	// Mode is a set of signals (Trace, Stats) the exporter supports.
+	Mode Mode
+	// Parse converts exporter URL (e.g. jaeger://localhost:14268) into Exporter.
+	// If the setting doesn't have scheme (e.g. jaeger), u has only Scheme and RawQuery.
+	// Exporter.Options is filled by u.Query() if Parse doesn't set it.
+	Parse func(u *url.URL) (*Exporter, error)
+	// New creates an exporter instance.
+	// It is called only once even if the same exporter is used for trace and stats.
+	// ctx is the one that is passed to InitWithConfig. logger is the one that is specified by WithLogger.
+	// Use it for errors in background (e.g. failed to send data).
+	New func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error)
+}
+
+var (
+	exporterFactoriesLock sync.RWMutex
+	exporterFactories     = make(map[string]*ExporterFactory)
+)
+
+// RegisterExporterFactory registers factory for the scheme of OC_TRACE_EXPORTER/OC_STATS_EXPORTER.
+//
+// Registering the same factory for several schemes makes aliases (e.g. stackdriver and sd).
+// Registering an existing scheme replaces the factory.
+func RegisterExporterFactory(scheme string, factory *ExporterFactory) {
+	if factory == nil || factory.Parse == nil || factory.New == nil {
+		panic(fmt.Sprintf("occonfig: invalid exporter factory for %s", scheme))
+	}
+	exporterFactoriesLock.Lock()
+	defer exporterFactoriesLock.Unlock()
+	exporterFactories[scheme] = factory
+}
+
+func lookupExporterFactory(scheme string) (*ExporterFactory, bool) {
+	exporterFactoriesLock.RLock()
+	defer exporterFactoriesLock.RUnlock()
+	factory, ok := exporterFactories[scheme]
+	return factory, ok
+}
+
+func selectExporter(host string, mode Mode) (*Exporter, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" { // no scheme
+		u = &url.URL{Scheme: u.Path, RawQuery: u.RawQuery}
+	}
+	factory, ok := lookupExporterFactory(u.Scheme)
+	if !ok || factory.Mode&mode != mode {
+		return nil, errors.New("No exporter config found")
+	}
+	exporter, err := factory.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	if exporter.Options == nil {
+		exporter.Options = u.Query()
+	}
+	exporter.factory = factory
+	exporter.scheme = u.Scheme
+	exporter.url = u
+	return exporter, nil
+}
+
+type instanceKey struct {
+	factory *ExporterFactory
+	host    string
+	options string
+}
+
+// exporterInstances shares exporter instances between trace and stats.
+type exporterInstances struct {
+	ctx       context.Context
+	config    *Config
+	logger    *log.Logger
+	instances map[instanceKey]*ExporterInstance
+	finalizes *[]func() error
+}
+
+func newExporterInstances(ctx context.Context, config *Config, logger *log.Logger, finalizes *[]func() error) *exporterInstances {
+	return &exporterInstances{
+		ctx:       ctx,
+		config:    config,
+		logger:    logger,
+		instances: make(map[instanceKey]*ExporterInstance),
+		finalizes: finalizes,
+	}
+}
+
+// applyExporterOptions fills the exporter options that are not in the URL query by Config.ExporterOptions.
+// If aliases of the factory (e.g. sd and stackdriver) have the same option, the scheme of the exporter URL has priority,
+// then the other aliases in alphabetical order.
+func applyExporterOptions(exporter *Exporter, config *Config) {
+	schemes := make([]string, 0, len(config.ExporterOptions))
+	for scheme := range config.ExporterOptions {
+		if scheme != exporter.scheme {
+			schemes = append(schemes, scheme)
+		}
+	}
+	sort.Strings(schemes)
+	if _, ok := config.ExporterOptions[exporter.scheme]; ok {
+		schemes = append([]string{exporter.scheme}, schemes...)
+	}
+	for _, scheme := range schemes {
+		if factory, ok := lookupExporterFactory(scheme); !ok || factory != exporter.factory {
+			continue
+		}
+		if exporter.Options == nil {
+			exporter.Options = url.Values{}
+		}
+		for key, value := range config.ExporterOptions[scheme] {
+			if _, ok := exporter.Options[key]; !ok {
+				exporter.Options[key] = value
+			}
+		}
+	}
+}
+
+func (e *exporterInstances) get(exporter *Exporter) (*ExporterInstance, error) {
+	applyExporterOptions(exporter, e.config)
+	key := instanceKey{
+		factory: exporter.factory,
+		host:    exporter.Host,
+		options: exporter.Options.Encode(),
+	}
+	if instance, ok := e.instances[key]; ok {
+		return instance, nil
+	}
+	instance, err := exporter.factory.New(e.ctx, exporter, e.config, e.logger)
+	if err != nil {
+		return nil, err
+	}
+	instance.name = exporter.factory.Name
+	e.instances[key] = instance
+	if instance.Finalize != nil {
+		name := instance.name
+		finalize := instance.Finalize
+		*e.finalizes = append(*e.finalizes, func() error {
+			if err := finalize(); err != nil {
+				return fmt.Errorf("Failed to flush %s exporter: %v", name, err)
+			}
+			return nil
+		})
+	}
+	return instance, nil
+}
@@ -0,0 +1,122 @@
+package occonfig
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/trace"
+)
+
+type nopTraceExporter struct{}
+
+func (nopTraceExporter) ExportSpan(s *trace.SpanData) {}
+
+const testCollector ExporterType = 1000
+
+func init() {
+	RegisterExporterFactory("test-collector", &ExporterFactory{
+		Name: "Test Collector",
+		Mode: Trace,
+		Parse: func(u *url.URL) (*Exporter, error) {
+			return &Exporter{
+				Type: testCollector,
+				Host: u.Host,
+			}, nil
+		},
+		New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+			return &ExporterInstance{
+				Trace:    nopTraceExporter{},
+				Finalize: func() error { return nil },
+			}, nil
+		},
+	})
+}
+
+func TestRegisterExporterFactory(t *testing.T) {
+	exporter, err := SelectTraceExporter("test-collector://collector:1234")
+	assert.Nil(t, err)
+	if exporter != nil {
+		assert.Equal(t, testCollector, exporter.Type)
+		assert.Equal(t, "collector:1234", exporter.Host)
+	}
+	exporter, err = SelectTraceExporter("test-collector")
+	assert.Nil(t, err)
+	if exporter != nil {
+		assert.Equal(t, testCollector, exporter.Type)
+		assert.Equal(t, "", exporter.Host)
+	}
+	_, err = SelectStatsExporter("test-collector://collector:1234")
+	assert.NotNil(t, err)
+}
+
+func TestCustomExporterType(t *testing.T) {
+	RegisterExporterFactory("untyped-collector", &ExporterFactory{
+		Name: "Untyped Collector",
+		Mode: Trace,
+		Parse: func(u *url.URL) (*Exporter, error) {
+			return &Exporter{Host: u.Host}, nil
+		},
+		New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+			return &ExporterInstance{Trace: nopTraceExporter{}}, nil
+		},
+	})
+	exporter, err := SelectTraceExporter("untyped-collector://collector:1234")
+	assert.Nil(t, err)
+	if exporter != nil {
+		assert.Equal(t, CUSTOM, exporter.Type)
+	}
+}
+
+func TestApplyExporterOptionsOfAliases(t *testing.T) {
+	config := &Config{ExporterOptions: map[string]url.Values{
+		"stackdriver": {"location": {"stackdriver"}, "metricPrefix": {"stackdriver"}},
+		"sd":          {"location": {"sd"}, "credentials": {"sd"}},
+	}}
+	for i := 0; i < 10; i++ {
+		exporter, err := SelectTraceExporter("sd://my-project")
+		assert.Nil(t, err)
+		applyExporterOptions(exporter, config)
+		assert.Equal(t, "sd", exporter.Options.Get("location"))
+		assert.Equal(t, "sd", exporter.Options.Get("credentials"))
+		assert.Equal(t, "stackdriver", exporter.Options.Get("metricPrefix"))
+
+		exporter, err = SelectTraceExporter("stackdriver://my-project")
+		assert.Nil(t, err)
+		applyExporterOptions(exporter, config)
+		assert.Equal(t, "stackdriver", exporter.Options.Get("location"))
+	}
+}
+
+func TestExporterInstancesAreShared(t *testing.T) {
+	var finalizes []func() error
+	instances := newExporterInstances(context.Background(), &Config{}, testLogger, &finalizes)
+
+	traceExporter, err := SelectTraceExporter("test-collector://collector:1234")
+	assert.Nil(t, err)
+	traceInstance, err := instances.get(traceExporter)
+	assert.Nil(t, err)
+
+	sameExporter, err := SelectTraceExporter("test-collector://collector:1234")
+	assert.Nil(t, err)
+	sameInstance, err := instances.get(sameExporter)
+	assert.Nil(t, err)
+	assert.True(t, traceInstance == sameInstance)
+
+	otherExporter, err := SelectTraceExporter("test-collector://collector:5678")
+	assert.Nil(t, err)
+	otherInstance, err := instances.get(otherExporter)
+	assert.Nil(t, err)
+	assert.False(t, traceInstance == otherInstance)
+
+	optionsExporter, err := SelectTraceExporter("test-collector://collector:1234?option=value")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", optionsExporter.Options.Get("option"))
+	optionsInstance, err := instances.get(optionsExporter)
+	assert.Nil(t, err)
+	assert.False(t, traceInstance == optionsInstance)
+
+	assert.Equal(t, 3, len(finalizes))
+}
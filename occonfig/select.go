@@ -2,15 +2,17 @@ package occonfig
 
 import (
 	"errors"
-	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 type ExporterType int
 
 const (
-	STACKDRIVER ExporterType = iota
+	// CUSTOM is the zero value. It is the type of exporters whose factory doesn't set Exporter.Type.
+	CUSTOM ExporterType = iota
+	STACKDRIVER
 	XRAY
 	DATADOG
 	JAEGER
@@ -19,195 +21,86 @@ const (
 	PROMETHEUS
 	GRAPHITE
 	ZAP
+	PUSHGATEWAY
+	OCAGENT
+	OTLP
 )
 
 type Exporter struct {
 	Type ExporterType
 	Host string
+	// Options has exporter specific settings in the URL query (e.g. datadog://localhost?service=my-service).
+	Options url.Values
+
+	factory *ExporterFactory
+	scheme  string
+	url     *url.URL
 }
 
 func SelectTraceExporter(host string) (*Exporter, error) {
 	u, _ := url.Parse(host)
-	switch u.Scheme {
-	case "sd":
-		fallthrough
-	case "stackdriver":
-		return &Exporter{
-			Type: STACKDRIVER,
-			Host: u.Host,
-		}, nil
-	case "xray":
-		return &Exporter{
-			Type: XRAY,
-		}, nil
-	case "dd":
-		fallthrough
-	case "datadog":
-		{
-			host := u.Hostname()
-			port := u.Port()
-			if host == "" {
-				host = "localhost"
-			}
-			if port == "" {
-				port = "8125"
-			}
-			return &Exporter{
-				Type: DATADOG,
-				Host: fmt.Sprintf("%s:%s", host, port),
-			}, nil
-
-		}
-	case "jaeger":
-		{
-			host := u.Hostname()
-			port := u.Port()
-			path := u.Path
-			if host == "" {
-				host = "localhost"
-			}
-			if port == "" {
-				port = "14268"
-			}
-			if path == "" {
-				path = "/api/traces"
-			}
-			return &Exporter{
-				Type: JAEGER,
-				Host: fmt.Sprintf("http://%s:%s%s", host, port, path),
-			}, nil
-		}
-	case "jeager":
+	if u != nil && u.Scheme == "jeager" {
 		return nil, errors.New("Misspelling! jeager -> jaeger")
-	case "zipkin":
-		{
-			host := u.Hostname()
-			port := u.Port()
-			path := u.Path
-			if host == "" {
-				host = "localhost"
-			}
-			if port == "" {
-				port = "9411"
-			}
-			if path == "" {
-				path = "/api/v2/spans"
-			}
-			return &Exporter{
-				Type: ZIPKIN,
-				Host: fmt.Sprintf("http://%s:%s%s", host, port, path),
-			}, nil
-		}
-	case "": // no scheme
-		switch u.Path {
-		case "xray":
-			return &Exporter{
-				Type: XRAY,
-			}, nil
-		case "dd":
-			fallthrough
-		case "datadog":
-			return &Exporter{
-				Type: DATADOG,
-				Host: "localhost:8125",
-			}, nil
-		case "honeycomb":
-			return &Exporter{
-				Type: HONEYCOMB,
-			}, nil
-		case "jaeger":
-			return &Exporter{
-				Type: JAEGER,
-				Host: "http://localhost:14268/api/traces",
-			}, nil
-		case "zipkin":
-			return &Exporter{
-				Type: ZIPKIN,
-				Host: "http://localhost:9411/api/v2/spans",
-			}, nil
-		case "zap":
-			return &Exporter{
-				Type: ZAP,
-			}, nil
-		}
 	}
-	return nil, errors.New("No exporter config found")
+	return selectExporter(host, Trace)
 }
 
 func SelectStatsExporter(host string) (*Exporter, error) {
-	u, _ := url.Parse(host)
-	switch u.Scheme {
-	case "sd":
-		fallthrough
-	case "stackdriver":
-		return &Exporter{
-			Type: STACKDRIVER,
-			Host: u.Host,
-		}, nil
-	case "dd":
-		fallthrough
-	case "datadog":
-		{
-			host := u.Hostname()
-			port := u.Port()
-			if host == "" {
-				host = "localhost"
-			}
-			if port == "" {
-				port = "8125"
-			}
-			return &Exporter{
-				Type: DATADOG,
-				Host: fmt.Sprintf("%s:%s", host, port),
-			}, nil
+	return selectExporter(host, Stats)
+}
 
-		}
-	case "prometheus":
-		fallthrough
-	case "p8s":
-		{
-			port := u.Port()
-			if port == "" {
-				port = "8888"
-			}
-			return &Exporter{
-				Type: PROMETHEUS,
-				Host: fmt.Sprintf("http://:%s", port),
-			}, nil
+// hostPort returns host and port of u. If they are empty, default values are used.
+func hostPort(u *url.URL, defaultHost, defaultPort string) (string, string) {
+	host := u.Hostname()
+	port := u.Port()
+	if host == "" {
+		host = defaultHost
+	}
+	if port == "" {
+		port = defaultPort
+	}
+	return host, port
+}
 
+// splitExporters splits comma separated exporter settings.
+func splitExporters(s string) []string {
+	var result []string
+	for _, exporter := range strings.Split(s, ",") {
+		exporter = strings.TrimSpace(exporter)
+		if exporter != "" {
+			result = append(result, exporter)
 		}
-	case "graphite":
-		{
-			host := u.Hostname()
-			port := u.Port()
-			if host == "" {
-				host = "localhost"
-			}
-			if port == "" {
-				port = "2003"
-			}
-			return &Exporter{
-				Type: GRAPHITE,
-				Host: fmt.Sprintf("%s:%s", host, port),
-			}, nil
-		}
-	case "": // no scheme
-		switch u.Path {
-		case "dd":
-			fallthrough
-		case "datadog":
-			return &Exporter{
-				Type: DATADOG,
-				Host: "localhost:8125",
-			}, nil
-		case "graphite":
-			return &Exporter{
-				Type: GRAPHITE,
-				Host: "localhost:2003",
-			}, nil
+	}
+	return result
+}
+
+// splitExporterURLs splits comma separated exporter URLs. Commas in the URL query (e.g. constLabels=env=prod,team=a)
+// are kept because only the token that starts with a registered scheme or "scheme://" starts a new exporter.
+func splitExporterURLs(s string) []string {
+	var result []string
+	for _, token := range splitExporters(s) {
+		if len(result) == 0 || startsExporterURL(token) {
+			result = append(result, token)
+		} else {
+			result[len(result)-1] += "," + token
 		}
 	}
-	return nil, errors.New("No exporter config found")
+	return result
+}
+
+func startsExporterURL(token string) bool {
+	u, err := url.Parse(token)
+	if err != nil {
+		return false
+	}
+	scheme := u.Scheme
+	if scheme == "" {
+		scheme = u.Path
+	}
+	if _, ok := lookupExporterFactory(scheme); ok {
+		return true
+	}
+	return u.Scheme != "" && strings.Contains(token, "://")
 }
 
 func SelectSampler(s string) (float64, error) {
@@ -0,0 +1,249 @@
+package occonfig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.opencensus.io/zpages"
+)
+
+// Listen policies that are used when the port of Prometheus or ZPage is not available.
+const (
+	// ListenFail makes Init return an error. It is the default policy.
+	ListenFail = "fail"
+	// ListenWarn makes Init print a warning and continue without the endpoint.
+	ListenWarn = "warn"
+	// ListenFreePort makes Init listen on a free port instead. OCConfig.Endpoints() returns the actual URLs.
+	ListenFreePort = "free-port"
+)
+
+func validateListenPolicy(policy string) error {
+	switch policy {
+	case "", ListenFail, ListenWarn, ListenFreePort:
+		return nil
+	}
+	return fmt.Errorf("Invalid listen policy %q. It should be '%s'|'%s'|'%s'", policy, ListenFail, ListenWarn, ListenFreePort)
+}
+
+// isEmbedded returns true if u is like "embedded:/debug".
+// Embedded endpoints are served by OCConfig.Handler() on the application's server.
+func isEmbedded(u *url.URL) bool {
+	return u.Scheme == "embedded"
+}
+
+type telemetryEndpoint struct {
+	name string
+	path string
+}
+
+// telemetryServer serves exporter handlers and ZPage on one port.
+// If embedded is true, it doesn't listen any port and it is served by OCConfig.Handler().
+// If tlsConfig is not nil, it serves HTTPS.
+type telemetryServer struct {
+	embedded  bool
+	host      string
+	port      string
+	tlsConfig *tls.Config
+	mux       *http.ServeMux
+	endpoints []telemetryEndpoint
+	listener  net.Listener
+	server    *http.Server
+}
+
+func (s *telemetryServer) names() string {
+	var names []string
+	for _, endpoint := range s.endpoints {
+		names = append(names, endpoint.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// newTelemetryServers builds a server for each port that exporter handlers and ZPage use.
+// Embedded endpoints are gathered into one server. Endpoints on the same port should have the same address and scheme.
+func newTelemetryServers(handlers []*ExporterInstance, zpage *url.URL) ([]*telemetryServer, error) {
+	var servers []*telemetryServer
+	paths := make(map[string]string)
+	getServer := func(name string, u *url.URL, tlsConfig *tls.Config) (*telemetryServer, error) {
+		for _, server := range servers {
+			if server.embedded == isEmbedded(u) && server.port == u.Port() {
+				if server.host != u.Hostname() || (server.tlsConfig != nil) != (u.Scheme == "https") {
+					return nil, fmt.Errorf("%s and %s use port %s with different addresses or schemes: %s", server.names(), name, u.Port(), u)
+				}
+				return server, nil
+			}
+		}
+		if u.Scheme == "https" && tlsConfig == nil {
+			return nil, fmt.Errorf("%s endpoint %s needs a server certificate", name, u)
+		}
+		server := &telemetryServer{
+			embedded:  isEmbedded(u),
+			host:      u.Hostname(),
+			port:      u.Port(),
+			tlsConfig: tlsConfig,
+			mux:       http.NewServeMux(),
+		}
+		servers = append(servers, server)
+		return server, nil
+	}
+	pathKey := func(u *url.URL) string {
+		if isEmbedded(u) {
+			return "embedded:" + u.Path
+		}
+		return u.Port() + u.Path
+	}
+	for _, handler := range handlers {
+		u := handler.Endpoint
+		if name, ok := paths[pathKey(u)]; ok {
+			return nil, fmt.Errorf("%s and %s uses same endpoints: %s", name, handler.name, u)
+		}
+		server, err := getServer(handler.name, u, handler.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+		server.mux.Handle(u.Path, handler.Handler)
+		server.endpoints = append(server.endpoints, telemetryEndpoint{name: handler.name, path: u.Path})
+		paths[pathKey(u)] = handler.name
+	}
+	if zpage != nil {
+		if name, ok := paths[pathKey(zpage)]; ok {
+			return nil, fmt.Errorf("ZPage and %s uses same endpoints: %s", name, zpage)
+		}
+		server, err := getServer("ZPage", zpage, nil)
+		if err != nil {
+			return nil, err
+		}
+		zpages.Handle(server.mux, zpage.Path)
+		server.endpoints = append(server.endpoints, telemetryEndpoint{name: "ZPage", path: zpage.Path})
+	}
+	return servers, nil
+}
+
+// embeddedHandler returns the handler for the embedded endpoints.
+func embeddedHandler(servers []*telemetryServer) http.Handler {
+	for _, server := range servers {
+		if server.embedded {
+			return server.mux
+		}
+	}
+	return http.NotFoundHandler()
+}
+
+// listen binds the port synchronously. If the port is not available, it follows policy.
+// With ListenWarn policy, the server's listener stays nil.
+func (s *telemetryServer) listen(policy string, logger *log.Logger) error {
+	if s.embedded {
+		return nil
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(s.host, s.port))
+	if err == nil {
+		s.listener = listener
+		return nil
+	}
+	switch policy {
+	case ListenWarn:
+		logger.Printf("[OpenCensus] Warning: %s endpoint is disabled. Failed to listen port %s: %v", s.names(), s.port, err)
+		return nil
+	case ListenFreePort:
+		listener, freeErr := net.Listen("tcp", net.JoinHostPort(s.host, "0"))
+		if freeErr != nil {
+			return fmt.Errorf("Failed to listen a free port for %s endpoint instead of port %s: %v", s.names(), s.port, freeErr)
+		}
+		s.listener = listener
+		logger.Printf("[OpenCensus] Port %s is not available (%v). %s endpoint uses port %s instead", s.port, err, s.names(), s.actualPort())
+		return nil
+	default:
+		return fmt.Errorf("Failed to listen port %s for %s endpoint: %v", s.port, s.names(), err)
+	}
+}
+
+func (s *telemetryServer) actualPort() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+// baseURL returns the URL of the server with the actual port. If the server listens all interfaces, the host is localhost.
+func (s *telemetryServer) baseURL() string {
+	scheme := "http"
+	if s.tlsConfig != nil {
+		scheme = "https"
+	}
+	host := s.host
+	if host == "" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, s.actualPort()))
+}
+
+// urls returns the URLs of the endpoints with the actual port.
+func (s *telemetryServer) urls() []string {
+	var result []string
+	if s.listener == nil {
+		return result
+	}
+	for _, endpoint := range s.endpoints {
+		result = append(result, s.baseURL()+endpoint.path)
+	}
+	return result
+}
+
+// serve starts HTTP server in background. It is stopped by OCConfig.Shutdown.
+func (s *telemetryServer) serve(logger *log.Logger) {
+	if s.embedded {
+		for _, endpoint := range s.endpoints {
+			logger.Printf("[OpenCensus] %s is served at %s by OCConfig.Handler()", endpoint.name, endpoint.path)
+		}
+		return
+	}
+	if s.listener == nil {
+		return
+	}
+	for _, endpoint := range s.endpoints {
+		if endpoint.name == "ZPage" {
+			printZPageInformation(s.baseURL(), endpoint.path, logger)
+		} else {
+			logger.Printf("Start waiting %s access at %s%s", endpoint.name, s.baseURL(), endpoint.path)
+		}
+	}
+	s.server = &http.Server{
+		Handler: s.mux,
+	}
+	listener := s.listener
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Printf("[OpenCensus] %s endpoint at port %s stopped: %v", s.names(), s.actualPort(), err)
+		}
+	}()
+}
+
+// startServers binds all ports, then starts the servers. If one of them fails, all listeners are closed.
+func startServers(servers []*telemetryServer, policy string, logger *log.Logger) error {
+	for _, server := range servers {
+		if err := server.listen(policy, logger); err != nil {
+			for _, s := range servers {
+				if s.listener != nil {
+					s.listener.Close()
+					s.listener = nil
+				}
+			}
+			return err
+		}
+	}
+	for _, server := range servers {
+		server.serve(logger)
+	}
+	return nil
+}
+
+func printZPageInformation(baseURL, path string, logger *log.Logger) {
+	logger.Printf("[OpenCensus] ZPage is initialized. The following URLs are available:")
+	logger.Printf("    %s%s/rpcz", baseURL, path)
+	logger.Printf("    %s%s/tracez", baseURL, path)
+}
@@ -0,0 +1,214 @@
+package occonfig
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHandler(t *testing.T, endpoint string) *ExporterInstance {
+	u, err := url.Parse(endpoint)
+	assert.Nil(t, err)
+	return &ExporterInstance{
+		Handler:  http.NotFoundHandler(),
+		Endpoint: u,
+		name:     "Prometheus",
+	}
+}
+
+func TestNewTelemetryServers(t *testing.T) {
+	servers, err := newTelemetryServers([]*ExporterInstance{
+		newTestHandler(t, "http://:8888/metrics"),
+		newTestHandler(t, "http://:8889/metrics"),
+	}, &url.URL{Scheme: "http", Host: ":8888", Path: "/debug"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(servers))
+
+	_, err = newTelemetryServers([]*ExporterInstance{
+		newTestHandler(t, "http://:8888/metrics"),
+		newTestHandler(t, "http://:8888/metrics"),
+	}, nil)
+	assert.NotNil(t, err)
+
+	_, err = newTelemetryServers([]*ExporterInstance{
+		newTestHandler(t, "http://:8888/metrics"),
+	}, &url.URL{Scheme: "http", Host: ":8888", Path: "/metrics"})
+	assert.NotNil(t, err)
+
+	_, err = newTelemetryServers([]*ExporterInstance{
+		newTestHandler(t, "http://127.0.0.1:8888/metrics"),
+	}, &url.URL{Scheme: "http", Host: ":8888", Path: "/debug"})
+	assert.NotNil(t, err)
+
+	_, err = newTelemetryServers([]*ExporterInstance{
+		newTestHandler(t, "https://:8888/metrics"),
+	}, nil)
+	assert.NotNil(t, err)
+}
+
+func freePort(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func TestShutdown(t *testing.T) {
+	port := freePort(t)
+	config := NewConfig()
+	config.StatsExporters = []string{"prometheus://:" + port}
+	config.ZPage = "http://:" + port + "/debug"
+	oc, err := InitWithConfig(context.Background(), config,
+		WithMode(Stats), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger))
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	res, err := http.Get("http://127.0.0.1:" + port + "/metrics")
+	assert.Nil(t, err)
+	if res != nil {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		res.Body.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, oc.Shutdown(ctx))
+
+	_, err = http.Get("http://127.0.0.1:" + port + "/metrics")
+	assert.NotNil(t, err)
+}
+
+func TestListenPolicy(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	initWithPolicy := func(policy string) (OCConfig, error) {
+		config := NewConfig()
+		config.StatsExporters = []string{"prometheus://:" + port}
+		config.ListenPolicy = policy
+		return InitWithConfig(context.Background(), config,
+			WithMode(Stats), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger))
+	}
+
+	_, err = initWithPolicy(ListenFail)
+	assert.NotNil(t, err)
+	if err != nil {
+		assert.Contains(t, err.Error(), "Prometheus")
+		assert.Contains(t, err.Error(), port)
+	}
+
+	oc, err := initWithPolicy(ListenWarn)
+	assert.Nil(t, err)
+	if err == nil {
+		assert.Equal(t, 0, len(oc.Endpoints()))
+		oc.Close()
+	}
+
+	oc, err = initWithPolicy(ListenFreePort)
+	assert.Nil(t, err)
+	if err == nil {
+		endpoints := oc.Endpoints()
+		assert.Equal(t, 1, len(endpoints))
+		if len(endpoints) == 1 {
+			assert.NotEqual(t, "http://localhost:"+port+"/metrics", endpoints[0])
+			res, err := http.Get(endpoints[0])
+			assert.Nil(t, err)
+			if err == nil {
+				assert.Equal(t, http.StatusOK, res.StatusCode)
+				res.Body.Close()
+			}
+		}
+		oc.Close()
+	}
+
+	_, err = initWithPolicy("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestEmbeddedHandler(t *testing.T) {
+	config := NewConfig()
+	config.StatsExporters = []string{"prometheus://embedded"}
+	config.ZPage = "embedded:/debug"
+	oc, err := InitWithConfig(context.Background(), config,
+		WithMode(Stats), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger))
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	defer oc.Close()
+	assert.Equal(t, 0, len(oc.Endpoints()))
+
+	mux := http.NewServeMux()
+	mux.Handle("/", oc.Handler())
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	for _, path := range []string{"/metrics", "/debug/rpcz", "/debug/tracez"} {
+		res, err := http.Get(server.URL + path)
+		assert.Nil(t, err)
+		if err == nil {
+			assert.Equal(t, http.StatusOK, res.StatusCode, path)
+			res.Body.Close()
+		}
+	}
+}
+
+func TestDeferredServerStart(t *testing.T) {
+	port := freePort(t)
+	config := NewConfig()
+	config.StatsExporters = []string{"prometheus://:" + port}
+	oc, err := InitWithConfig(context.Background(), config,
+		WithMode(Stats), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger),
+		WithDeferredServerStart())
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	defer oc.Close()
+	assert.Equal(t, 0, len(oc.Endpoints()))
+	_, err = http.Get("http://127.0.0.1:" + port + "/metrics")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, oc.StartServer())
+	res, err := http.Get("http://127.0.0.1:" + port + "/metrics")
+	assert.Nil(t, err)
+	if err == nil {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		res.Body.Close()
+	}
+	assert.Nil(t, oc.StartServer())
+}
+
+func TestDeferredServerStartError(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	config := NewConfig()
+	config.StatsExporters = []string{"prometheus://:" + port}
+	oc, err := InitWithConfig(context.Background(), config,
+		WithMode(Stats), WithEnv(map[string]string{}), WithoutCommandLine(), WithLogger(testLogger),
+		WithDeferredServerStart())
+	assert.Nil(t, err)
+	if err != nil {
+		return
+	}
+	defer oc.Close()
+	assert.NotNil(t, oc.StartServer())
+}
@@ -0,0 +1,122 @@
+package occonfig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"contrib.go.opencensus.io/exporter/stackdriver"
+	"contrib.go.opencensus.io/exporter/stackdriver/monitoredresource"
+	"google.golang.org/api/option"
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+// parseLabels parses comma separated labels like "env=prod,team=a".
+func parseLabels(name, s string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, label := range splitExporters(s) {
+		i := strings.Index(label, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("Invalid %s %q. It should be key=value", name, label)
+		}
+		result[label[:i]] = label[i+1:]
+	}
+	return result, nil
+}
+
+// stackdriverOptions builds options from stackdriver://project-id?credentials=key.json&metricPrefix=prefix&location=asia-northeast1
+// &bundleDelayThreshold=10s&bundleCountThreshold=100&labels=env=prod&resourceType=gce_instance&resourceLabels=zone=asia-northeast1-a.
+// resourceType=auto detects the monitored resource from the environment (GCE, GKE, AWS EC2).
+func stackdriverOptions(ctx context.Context, exporter *Exporter, logger *log.Logger) (stackdriver.Options, error) {
+	options := stackdriver.Options{
+		ProjectID:    exporter.Host,
+		Context:      ctx,
+		Location:     exporter.Options.Get("location"),
+		MetricPrefix: exporter.Options.Get("metricPrefix"),
+		OnError: func(err error) {
+			logger.Printf("Failed to export to GCP StackDriver: %v", err)
+		},
+	}
+	if credentials := exporter.Options.Get("credentials"); credentials != "" {
+		options.MonitoringClientOptions = []option.ClientOption{option.WithCredentialsFile(credentials)}
+		options.TraceClientOptions = []option.ClientOption{option.WithCredentialsFile(credentials)}
+	}
+	if rawDelay := exporter.Options.Get("bundleDelayThreshold"); rawDelay != "" {
+		delay, err := time.ParseDuration(rawDelay)
+		if err != nil || delay <= 0 {
+			return stackdriver.Options{}, fmt.Errorf("Invalid Stackdriver bundleDelayThreshold %q. It should be a positive duration like 10s", rawDelay)
+		}
+		options.BundleDelayThreshold = delay
+	}
+	if rawCount := exporter.Options.Get("bundleCountThreshold"); rawCount != "" {
+		count, err := strconv.Atoi(rawCount)
+		if err != nil || count <= 0 {
+			return stackdriver.Options{}, fmt.Errorf("Invalid Stackdriver bundleCountThreshold %q. It should be a positive number", rawCount)
+		}
+		options.BundleCountThreshold = count
+	}
+	if _, ok := exporter.Options["labels"]; ok {
+		labels, err := parseLabels("Stackdriver label", strings.Join(exporter.Options["labels"], ","))
+		if err != nil {
+			return stackdriver.Options{}, err
+		}
+		// empty labels disable the default opencensus_task label
+		options.DefaultMonitoringLabels = &stackdriver.Labels{}
+		for key, value := range labels {
+			options.DefaultMonitoringLabels.Set(key, value, "")
+		}
+	}
+	switch resourceType := exporter.Options.Get("resourceType"); resourceType {
+	case "":
+	case "auto":
+		options.MonitoredResource = monitoredresource.Autodetect()
+	default:
+		labels, err := parseLabels("Stackdriver resource label", strings.Join(exporter.Options["resourceLabels"], ","))
+		if err != nil {
+			return stackdriver.Options{}, err
+		}
+		options.Resource = &monitoredrespb.MonitoredResource{
+			Type:   resourceType,
+			Labels: labels,
+		}
+	}
+	return options, nil
+}
+
+var stackdriverFactory = &ExporterFactory{
+	Name: "GCP StackDriver",
+	Mode: Trace | Stats,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		return &Exporter{
+			Type: STACKDRIVER,
+			Host: u.Host,
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		options, err := stackdriverOptions(ctx, exporter, logger)
+		if err != nil {
+			return nil, err
+		}
+		sd, err := stackdriver.NewExporter(options)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create the GCP StackDriver exporter: %v", err)
+		}
+		return &ExporterInstance{
+			Trace: sd,
+			Stats: sd,
+			Finalize: func() error {
+				sd.Flush()
+				return nil
+			},
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("stackdriver", stackdriverFactory)
+	RegisterExporterFactory("sd", stackdriverFactory)
+}
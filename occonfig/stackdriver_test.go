@@ -0,0 +1,108 @@
+package occonfig
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStackdriverOptions(t *testing.T) {
+	testcases := []struct {
+		Name                 string
+		Source               string
+		Options              map[string]url.Values
+		ProjectID            string
+		Location             string
+		MetricPrefix         string
+		ClientOptions        int
+		BundleDelayThreshold time.Duration
+		BundleCountThreshold int
+		Labels               bool
+		ResourceType         string
+		ResourceLabels       map[string]string
+		Error                bool
+	}{
+		{
+			Name:      "project only",
+			Source:    "stackdriver://my-project",
+			ProjectID: "my-project",
+		},
+		{
+			Name:                 "query",
+			Source:               "sd://my-project?credentials=key.json&metricPrefix=custom.googleapis.com/my&location=asia-northeast1&bundleDelayThreshold=10s&bundleCountThreshold=50",
+			ProjectID:            "my-project",
+			Location:             "asia-northeast1",
+			MetricPrefix:         "custom.googleapis.com/my",
+			ClientOptions:        1,
+			BundleDelayThreshold: 10 * time.Second,
+			BundleCountThreshold: 50,
+		},
+		{
+			Name:      "JSON options",
+			Source:    "stackdriver://my-project",
+			Options:   map[string]url.Values{"stackdriver": {"credentials": {"key.json"}, "labels": {"env=prod", "team=a"}}},
+			ProjectID: "my-project",
+			Labels:    true,
+			// credentials are used for both monitoring and trace clients
+			ClientOptions: 1,
+		},
+		{
+			Name:      "empty labels",
+			Source:    "stackdriver://my-project?labels=",
+			ProjectID: "my-project",
+			Labels:    true,
+		},
+		{
+			Name:           "monitored resource",
+			Source:         "stackdriver://my-project?resourceType=gce_instance&resourceLabels=instance_id=1234,zone=asia-northeast1-a",
+			ProjectID:      "my-project",
+			ResourceType:   "gce_instance",
+			ResourceLabels: map[string]string{"instance_id": "1234", "zone": "asia-northeast1-a"},
+		},
+		{
+			Name:   "invalid bundle delay",
+			Source: "stackdriver://my-project?bundleDelayThreshold=10",
+			Error:  true,
+		},
+		{
+			Name:   "invalid bundle count",
+			Source: "stackdriver://my-project?bundleCountThreshold=many",
+			Error:  true,
+		},
+		{
+			Name:   "invalid labels",
+			Source: "stackdriver://my-project?labels=env",
+			Error:  true,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			exporter, err := SelectTraceExporter(testcase.Source)
+			assert.Nil(t, err)
+			applyExporterOptions(exporter, &Config{ExporterOptions: testcase.Options})
+			options, err := stackdriverOptions(context.Background(), exporter, testLogger)
+			if testcase.Error {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.ProjectID, options.ProjectID)
+			assert.Equal(t, testcase.Location, options.Location)
+			assert.Equal(t, testcase.MetricPrefix, options.MetricPrefix)
+			assert.Equal(t, testcase.ClientOptions, len(options.MonitoringClientOptions))
+			assert.Equal(t, testcase.ClientOptions, len(options.TraceClientOptions))
+			assert.Equal(t, testcase.BundleDelayThreshold, options.BundleDelayThreshold)
+			assert.Equal(t, testcase.BundleCountThreshold, options.BundleCountThreshold)
+			assert.Equal(t, testcase.Labels, options.DefaultMonitoringLabels != nil)
+			if testcase.ResourceType == "" {
+				assert.Nil(t, options.Resource)
+			} else if assert.NotNil(t, options.Resource) {
+				assert.Equal(t, testcase.ResourceType, options.Resource.Type)
+				assert.Equal(t, testcase.ResourceLabels, options.Resource.Labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package occonfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// httpScheme returns "https" if the exporter URL is like zipkin+https://host or zipkin://host?tls=true.
+func httpScheme(u *url.URL) string {
+	if strings.HasSuffix(u.Scheme, "+https") || u.Query().Get("tls") == "true" {
+		return "https"
+	}
+	return "http"
+}
+
+// httpEndpoint returns Exporter.Host with https scheme if tls option is true.
+// Parse decides the scheme by the URL, but tls option in JSON is applied after that. Use it in ExporterFactory.New.
+func httpEndpoint(exporter *Exporter) string {
+	if exporter.Options.Get("tls") == "true" && strings.HasPrefix(exporter.Host, "http://") {
+		return "https://" + strings.TrimPrefix(exporter.Host, "http://")
+	}
+	return exporter.Host
+}
+
+// newTLSConfig creates tls.Config from the URL query (caCert, clientCert, clientKey, insecureSkipVerify) and Config.
+// The URL query has higher priority. It returns nil if there is no TLS setting.
+func newTLSConfig(exporter *Exporter, config *Config) (*tls.Config, error) {
+	option := func(key, value string) string {
+		if _, ok := exporter.Options[key]; ok {
+			return exporter.Options.Get(key)
+		}
+		return value
+	}
+	caCert := option("caCert", config.TLSCACert)
+	clientCert := option("clientCert", config.TLSClientCert)
+	clientKey := option("clientKey", config.TLSClientKey)
+	insecureSkipVerify := config.TLSInsecureSkipVerify != nil && *config.TLSInsecureSkipVerify
+	if rawValue := exporter.Options.Get("insecureSkipVerify"); rawValue != "" {
+		value, err := strconv.ParseBool(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value insecureSkipVerify %q. It should be 'true'|'false'", rawValue)
+		}
+		insecureSkipVerify = value
+	}
+	if caCert == "" && clientCert == "" && clientKey == "" && !insecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+	if caCert != "" {
+		pem, err := ioutil.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read CA certificate: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Failed to parse CA certificate %s", caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if clientCert != "" || clientKey != "" {
+		if clientCert == "" || clientKey == "" {
+			return nil, fmt.Errorf("Both client certificate and client key are needed for mTLS")
+		}
+		certificate, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,306 @@
+package occonfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCertificate writes a self-signed certificate and its key into dir.
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "occonfig-test-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	assert.Nil(t, ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certPath, keyPath
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "occonfig")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	certPath, keyPath := writeCertificate(t, dir)
+
+	testcases := []struct {
+		Name               string
+		Source             string
+		Config             *Config
+		Nil                bool
+		InsecureSkipVerify bool
+		RootCAs            bool
+		Certificates       int
+		Error              bool
+	}{
+		{
+			Name:   "no settings",
+			Source: "zipkin+https://collector",
+			Config: &Config{},
+			Nil:    true,
+		},
+		{
+			Name:               "insecure skip verify in config",
+			Source:             "zipkin+https://collector",
+			Config:             &Config{TLSInsecureSkipVerify: boolPtr(true)},
+			InsecureSkipVerify: true,
+		},
+		{
+			Name:   "insecure skip verify is overwritten by query",
+			Source: "zipkin+https://collector?insecureSkipVerify=false",
+			Config: &Config{TLSInsecureSkipVerify: boolPtr(true)},
+			Nil:    true,
+		},
+		{
+			Name:    "CA certificate",
+			Source:  "zipkin+https://collector?caCert=" + certPath,
+			Config:  &Config{},
+			RootCAs: true,
+		},
+		{
+			Name:         "client certificate",
+			Source:       "zipkin+https://collector",
+			Config:       &Config{TLSClientCert: certPath, TLSClientKey: keyPath},
+			Certificates: 1,
+		},
+		{
+			Name:   "client certificate without key",
+			Source: "zipkin+https://collector",
+			Config: &Config{TLSClientCert: certPath},
+			Error:  true,
+		},
+		{
+			Name:   "invalid CA certificate",
+			Source: "zipkin+https://collector",
+			Config: &Config{TLSCACert: keyPath},
+			Error:  true,
+		},
+		{
+			Name:   "missing CA certificate",
+			Source: "zipkin+https://collector",
+			Config: &Config{TLSCACert: filepath.Join(dir, "missing.crt")},
+			Error:  true,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			exporter, err := SelectTraceExporter(testcase.Source)
+			assert.Nil(t, err)
+			tlsConfig, err := newTLSConfig(exporter, testcase.Config)
+			if testcase.Error {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			if testcase.Nil {
+				assert.Nil(t, tlsConfig)
+				return
+			}
+			if assert.NotNil(t, tlsConfig) {
+				assert.Equal(t, testcase.InsecureSkipVerify, tlsConfig.InsecureSkipVerify)
+				assert.Equal(t, testcase.RootCAs, tlsConfig.RootCAs != nil)
+				assert.Equal(t, testcase.Certificates, len(tlsConfig.Certificates))
+			}
+		})
+	}
+}
+
+func TestHTTPClientOverTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "occonfig")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	certPath, keyPath := writeCertificate(t, dir)
+
+	var lock sync.Mutex
+	var clientNames []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		for _, certificate := range r.TLS.PeerCertificates {
+			clientNames = append(clientNames, certificate.Subject.CommonName)
+		}
+		lock.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	caPath := filepath.Join(dir, "ca.crt")
+	assert.Nil(t, ioutil.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+
+	exporter, err := SelectTraceExporter(strings.Replace(server.URL, "https://", "zipkin+https://", 1))
+	assert.Nil(t, err)
+	config := &Config{
+		TLSCACert:     caPath,
+		TLSClientCert: certPath,
+		TLSClientKey:  keyPath,
+	}
+	client, err := newHTTPClient(exporter, config)
+	assert.Nil(t, err)
+	res, err := client.Post(exporter.Host, "application/json", strings.NewReader("[]"))
+	if assert.Nil(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	}
+
+	// without CA certificate, the server certificate can't be verified
+	client, err = newHTTPClient(exporter, &Config{})
+	assert.Nil(t, err)
+	_, err = client.Post(exporter.Host, "application/json", strings.NewReader("[]"))
+	assert.NotNil(t, err)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{"occonfig-test-client"}, clientNames)
+}
+
+func TestJaegerCustomTLS(t *testing.T) {
+	testcases := []struct {
+		Name    string
+		Source  string
+		Config  *Config
+		Error   bool
+		Warning bool
+	}{
+		{"insecureSkipVerify query", "jaeger+https://collector?insecureSkipVerify=true", &Config{}, true, false},
+		{"caCert query", "jaeger+https://collector?caCert=ca.pem", &Config{}, true, false},
+		{"global insecureSkipVerify", "jaeger+https://collector", &Config{TLSInsecureSkipVerify: boolPtr(true)}, false, true},
+		{"global CA certificate", "jaeger+https://collector", &Config{TLSCACert: "ca.pem"}, false, true},
+		{"no TLS settings", "jaeger+https://collector", &Config{}, false, false},
+		{"http doesn't use TLS", "jaeger://collector", &Config{TLSCACert: "ca.pem"}, false, false},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			exporter, err := SelectTraceExporter(testcase.Source)
+			assert.Nil(t, err)
+			var buf bytes.Buffer
+			_, err = jaegerOptions(exporter, testcase.Config, log.New(&buf, "", 0))
+			assert.Equal(t, testcase.Error, err != nil)
+			assert.Equal(t, testcase.Warning, strings.Contains(buf.String(), "Warning"))
+		})
+	}
+}
+
+func TestHTTPEndpointByJSONOption(t *testing.T) {
+	testcases := []struct {
+		Source   string
+		Scheme   string
+		Endpoint string
+	}{
+		{"zipkin://collector", "zipkin", "https://collector:9411/api/v2/spans"},
+		{"jaeger://collector", "jaeger", "https://collector:14268/api/traces"},
+		{"otlp+http://collector", "otlp+http", "https://collector:4318"},
+		{"otlp://collector", "otlp", "collector:4317"},
+		{"jaeger+https://collector", "jaeger", "https://collector:14268/api/traces"},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Source, func(t *testing.T) {
+			exporter, err := SelectTraceExporter(testcase.Source)
+			assert.Nil(t, err)
+			applyExporterOptions(exporter, &Config{ExporterOptions: map[string]url.Values{testcase.Scheme: {"tls": {"true"}}}})
+			assert.Equal(t, testcase.Endpoint, httpEndpoint(exporter))
+		})
+	}
+	for _, source := range []string{"pushgateway://gateway", "prometheus://:8888"} {
+		t.Run(source, func(t *testing.T) {
+			exporter, err := SelectStatsExporter(source)
+			assert.Nil(t, err)
+			applyExporterOptions(exporter, &Config{ExporterOptions: map[string]url.Values{strings.Split(source, ":")[0]: {"tls": {"true"}}}})
+			assert.True(t, strings.HasPrefix(httpEndpoint(exporter), "https://"))
+		})
+	}
+}
+
+func TestZipkinTLSByJSONOption(t *testing.T) {
+	var lock sync.Mutex
+	var count int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		count++
+		lock.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	exporter, err := SelectTraceExporter(strings.Replace(server.URL, "https://", "zipkin://", 1) + "/api/v2/spans")
+	assert.Nil(t, err)
+	config := &Config{
+		ServiceName:     "my-service",
+		ExporterOptions: map[string]url.Values{"zipkin": {"tls": {"true"}, "insecureSkipVerify": {"true"}}},
+	}
+	applyExporterOptions(exporter, config)
+	instance, err := zipkinFactory.New(context.Background(), exporter, config, testLogger)
+	assert.Nil(t, err)
+	exportTestSpan(t, instance)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 1, count)
+}
+
+func TestTLSInsecureSkipVerifyOverride(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Env      map[string]string
+		Explicit *Config
+		Expected bool
+	}{
+		{"JSON file", map[string]string{"OC_CONFIG_JSON": "insecure.json"}, nil, true},
+		{"env overrides JSON file", map[string]string{"OC_CONFIG_JSON": "insecure.json", "OC_TLS_INSECURE_SKIP_VERIFY": "false"}, nil, false},
+		{"explicit config overrides env", map[string]string{"OC_TLS_INSECURE_SKIP_VERIFY": "true"}, &Config{TraceSampler: -1, TLSInsecureSkipVerify: boolPtr(false)}, false},
+		{"explicit config without the setting", map[string]string{"OC_TLS_INSECURE_SKIP_VERIFY": "true"}, &Config{TraceSampler: -1}, true},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			options := newInitOptions([]Option{
+				WithEnv(testcase.Env),
+				WithWorkingDir("testdata"),
+				WithoutCommandLine(),
+			})
+			config, err := getConfig(testcase.Explicit, options)
+			assert.Nil(t, err)
+			if err != nil {
+				return
+			}
+			if assert.NotNil(t, config.TLSInsecureSkipVerify) {
+				assert.Equal(t, testcase.Expected, *config.TLSInsecureSkipVerify)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
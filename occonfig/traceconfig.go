@@ -0,0 +1,119 @@
+package occonfig
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"go.opencensus.io/trace"
+)
+
+var (
+	traceConfigLock sync.Mutex
+	// appliedTraceConfig is the global trace.Config as far as occonfig knows.
+	// The initial value is the default of OpenCensus.
+	appliedTraceConfig = trace.Config{
+		DefaultSampler: trace.ProbabilitySampler(1e-4),
+		IDGenerator:    newRandomIDGenerator(),
+	}
+)
+
+// newTraceConfig returns trace.Config for config. If TraceIDGenerator is empty, the X-Ray generator is used
+// when one of exporters is X-Ray. Otherwise, the current generator is kept.
+func newTraceConfig(config *Config, exporters []*Exporter) (trace.Config, error) {
+	result := trace.Config{
+		DefaultSampler: newSampler(config.TraceSampler, config.TraceSamplerParent),
+	}
+	switch config.TraceIDGenerator {
+	case "":
+		for _, exporter := range exporters {
+			if exporter.Type == XRAY {
+				result.IDGenerator = NewXRayIDGenerator()
+				break
+			}
+		}
+	case IDGeneratorXRay:
+		result.IDGenerator = NewXRayIDGenerator()
+	case IDGeneratorRandom:
+		result.IDGenerator = newRandomIDGenerator()
+	default:
+		return result, fmt.Errorf("Invalid trace ID generator %q. It should be '%s'|'%s'", config.TraceIDGenerator, IDGeneratorRandom, IDGeneratorXRay)
+	}
+	return result, nil
+}
+
+// Values of Config.TraceSamplerParent.
+const (
+	// SamplerParentBased samples spans whose remote parent is sampled and drops spans whose remote parent is not sampled.
+	// Root spans are sampled by the rate (e.g. OTEL_TRACES_SAMPLER=parentbased_traceidratio).
+	SamplerParentBased = "parentbased"
+	// SamplerParentIgnored samples all spans by the rate even if they have the remote parent (e.g. OTEL_TRACES_SAMPLER=traceidratio).
+	SamplerParentIgnored = "ignore"
+)
+
+// newSampler returns the sampler of the rate. If parent is empty, the probability sampler of OpenCensus is used for
+// the rate between 0 and 1. It samples spans whose parent is sampled.
+func newSampler(fraction float64, parent string) trace.Sampler {
+	switch parent {
+	case SamplerParentBased:
+		return parentBasedSampler(traceIDRatioSampler(fraction))
+	case SamplerParentIgnored:
+		return traceIDRatioSampler(fraction)
+	}
+	switch fraction {
+	case 0.0:
+		return trace.NeverSample()
+	case 1.0:
+		return trace.AlwaysSample()
+	default:
+		return trace.ProbabilitySampler(fraction)
+	}
+}
+
+// traceIDRatioSampler samples spans by the trace ID like trace.ProbabilitySampler, but it doesn't see the parent.
+func traceIDRatioSampler(fraction float64) trace.Sampler {
+	if fraction <= 0 {
+		return trace.NeverSample()
+	}
+	if fraction >= 1 {
+		return trace.AlwaysSample()
+	}
+	traceIDUpperBound := uint64(fraction * (1 << 63))
+	return func(p trace.SamplingParameters) trace.SamplingDecision {
+		x := binary.BigEndian.Uint64(p.TraceID[0:8]) >> 1
+		return trace.SamplingDecision{Sample: x < traceIDUpperBound}
+	}
+}
+
+// parentBasedSampler follows the decision of the parent. The root sampler is used for spans without the parent.
+// OpenCensus calls the default sampler only for root spans and spans of remote parents.
+func parentBasedSampler(root trace.Sampler) trace.Sampler {
+	return func(p trace.SamplingParameters) trace.SamplingDecision {
+		if p.ParentContext.SpanID != (trace.SpanID{}) {
+			return trace.SamplingDecision{Sample: p.ParentContext.IsSampled()}
+		}
+		return root(p)
+	}
+}
+
+// applyTraceConfig applies config by trace.ApplyConfig and returns the function that restores the previous config.
+// trace.Config that is applied without occonfig can't be restored because OpenCensus doesn't provide a getter.
+func applyTraceConfig(config trace.Config) func() error {
+	traceConfigLock.Lock()
+	defer traceConfigLock.Unlock()
+	previous := appliedTraceConfig
+	trace.ApplyConfig(config)
+	if config.DefaultSampler != nil {
+		appliedTraceConfig.DefaultSampler = config.DefaultSampler
+	}
+	if config.IDGenerator != nil {
+		appliedTraceConfig.IDGenerator = config.IDGenerator
+	}
+	return func() error {
+		traceConfigLock.Lock()
+		defer traceConfigLock.Unlock()
+		trace.ApplyConfig(previous)
+		appliedTraceConfig = previous
+		return nil
+	}
+}
@@ -0,0 +1,46 @@
+package occonfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/trace"
+)
+
+func TestNewSampler(t *testing.T) {
+	sampledParent := trace.SpanContext{SpanID: trace.SpanID{1}, TraceOptions: 1}
+	notSampledParent := trace.SpanContext{SpanID: trace.SpanID{1}}
+	// the first 8 bytes >> 1 is 2^62, so it is sampled only if the fraction is greater than 0.5
+	traceID := trace.TraceID{0x80}
+	testcases := []struct {
+		Name             string
+		Fraction         float64
+		Parent           string
+		Root             bool
+		SampledParent    bool
+		NotSampledParent bool
+	}{
+		{"OpenCensus always", 1, "", true, true, true},
+		{"OpenCensus never", 0, "", false, false, false},
+		{"OpenCensus probability", 0.25, "", false, true, false},
+		{"always_on", 1, SamplerParentIgnored, true, true, true},
+		{"always_off", 0, SamplerParentIgnored, false, false, false},
+		{"traceidratio", 0.25, SamplerParentIgnored, false, false, false},
+		{"traceidratio (sampled by trace ID)", 0.75, SamplerParentIgnored, true, true, true},
+		{"parentbased_always_on", 1, SamplerParentBased, true, true, false},
+		{"parentbased_always_off", 0, SamplerParentBased, false, true, false},
+		{"parentbased_traceidratio", 0.75, SamplerParentBased, true, true, false},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			sampler := newSampler(testcase.Fraction, testcase.Parent)
+			assert.Equal(t, testcase.Root, sampler(trace.SamplingParameters{TraceID: traceID}).Sample)
+			assert.Equal(t, testcase.SampledParent, sampler(trace.SamplingParameters{
+				TraceID: traceID, ParentContext: sampledParent, HasRemoteParent: true,
+			}).Sample)
+			assert.Equal(t, testcase.NotSampledParent, sampler(trace.SamplingParameters{
+				TraceID: traceID, ParentContext: notSampledParent, HasRemoteParent: true,
+			}).Sample)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package occonfig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	xray "contrib.go.opencensus.io/exporter/aws"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awsxray "github.com/aws/aws-sdk-go/service/xray"
+)
+
+var xrayOrigins = map[string]xray.Option{
+	"ec2": xray.WithOrigin(xray.OriginEC2),
+	"ecs": xray.WithOrigin(xray.OriginECS),
+	"eb":  xray.WithOrigin(xray.OriginEB),
+}
+
+// xrayOptions builds options from xray://region?origin=ecs&bufferSize=50&bufferPeriod=1s&endpoint=http://localhost:2000.
+// endpoint is the X-Ray API endpoint (e.g. the HTTP proxy of the X-Ray daemon).
+func xrayOptions(exporter *Exporter) ([]xray.Option, error) {
+	options := []xray.Option{xray.WithVersion("latest")}
+	region := exporter.Host
+	if region != "" {
+		options = append(options, xray.WithRegion(region))
+	}
+	if rawOrigin := exporter.Options.Get("origin"); rawOrigin != "" {
+		origin, ok := xrayOrigins[strings.ToLower(rawOrigin)]
+		if !ok {
+			return nil, fmt.Errorf("Invalid X-Ray origin %q. It should be 'ec2'|'ecs'|'eb'", rawOrigin)
+		}
+		options = append(options, origin)
+	}
+	if rawBufferSize := exporter.Options.Get("bufferSize"); rawBufferSize != "" {
+		bufferSize, err := strconv.Atoi(rawBufferSize)
+		if err != nil || bufferSize <= 0 || bufferSize > 50 {
+			return nil, fmt.Errorf("Invalid X-Ray bufferSize %q. It should be 1-50", rawBufferSize)
+		}
+		options = append(options, xray.WithBufferSize(bufferSize))
+	}
+	if rawBufferPeriod := exporter.Options.Get("bufferPeriod"); rawBufferPeriod != "" {
+		bufferPeriod, err := time.ParseDuration(rawBufferPeriod)
+		if err != nil || bufferPeriod <= 0 {
+			return nil, fmt.Errorf("Invalid X-Ray bufferPeriod %q. It should be a positive duration like 1s", rawBufferPeriod)
+		}
+		options = append(options, xray.WithInterval(bufferPeriod))
+	}
+	if endpoint := exporter.Options.Get("endpoint"); endpoint != "" {
+		awsConfig := &aws.Config{
+			Endpoint: aws.String(endpoint),
+		}
+		if region != "" {
+			awsConfig.Region = aws.String(region)
+		}
+		s, err := session.NewSession(awsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create AWS session for X-Ray endpoint %s: %v", endpoint, err)
+		}
+		options = append(options, xray.WithAPI(awsxray.New(s)))
+	}
+	return options, nil
+}
+
+var xrayFactory = &ExporterFactory{
+	Name: "AWS X-Ray",
+	Mode: Trace,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		return &Exporter{
+			Type: XRAY,
+			Host: u.Host,
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		options, err := xrayOptions(exporter)
+		if err != nil {
+			return nil, err
+		}
+		xe, err := xray.NewExporter(options...)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create the AWS X-Ray exporter: %v", err)
+		}
+		return &ExporterInstance{
+			Trace:    xe,
+			Finalize: xe.Close,
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("xray", xrayFactory)
+}
@@ -0,0 +1,106 @@
+package occonfig
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXRayOptions(t *testing.T) {
+	testcases := []struct {
+		Name   string
+		Source string
+		Count  int
+		Error  bool
+	}{
+		{
+			Name:   "default",
+			Source: "xray",
+			Count:  1,
+		},
+		{
+			Name:   "region",
+			Source: "xray://ap-northeast-1",
+			Count:  2,
+		},
+		{
+			Name:   "all options",
+			Source: "xray://ap-northeast-1?origin=ECS&bufferSize=10&bufferPeriod=2s&endpoint=http://localhost:2000",
+			Count:  6,
+		},
+		{
+			Name:   "invalid origin",
+			Source: "xray://ap-northeast-1?origin=lambda",
+			Error:  true,
+		},
+		{
+			Name:   "invalid buffer size",
+			Source: "xray://ap-northeast-1?bufferSize=100",
+			Error:  true,
+		},
+		{
+			Name:   "invalid buffer period",
+			Source: "xray://ap-northeast-1?bufferPeriod=1",
+			Error:  true,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			exporter, err := SelectTraceExporter(testcase.Source)
+			assert.Nil(t, err)
+			options, err := xrayOptions(exporter)
+			if testcase.Error {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.Count, len(options))
+		})
+	}
+}
+
+func TestXRayExporterWithEndpoint(t *testing.T) {
+	var lock sync.Mutex
+	var paths []string
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lock.Lock()
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, string(body))
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"UnprocessedTraceSegments": []}`))
+	}))
+	defer server.Close()
+
+	for key, value := range map[string]string{"AWS_ACCESS_KEY_ID": "dummy", "AWS_SECRET_ACCESS_KEY": "dummy"} {
+		oldValue, ok := os.LookupEnv(key)
+		os.Setenv(key, value)
+		if ok {
+			defer os.Setenv(key, oldValue)
+		} else {
+			defer os.Unsetenv(key)
+		}
+	}
+
+	exporter, err := SelectTraceExporter("xray://ap-northeast-1?origin=ec2&endpoint=" + server.URL)
+	assert.Nil(t, err)
+	instance, err := xrayFactory.New(context.Background(), exporter, &Config{ServiceName: "my-service"}, testLogger)
+	assert.Nil(t, err)
+	exportTestSpan(t, instance)
+
+	lock.Lock()
+	defer lock.Unlock()
+	if assert.Equal(t, 1, len(paths)) {
+		assert.Equal(t, "/TraceSegments", paths[0])
+		assert.True(t, strings.Contains(bodies[0], "AWS::EC2::Instance"))
+	}
+}
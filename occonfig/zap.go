@@ -0,0 +1,28 @@
+package occonfig
+
+import (
+	"context"
+	"log"
+	"net/url"
+
+	"github.com/future-architect/futureot/exporters/opencensus-go-exporter-zap"
+)
+
+var zapFactory = &ExporterFactory{
+	Name: "zap",
+	Mode: Trace,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		return &Exporter{
+			Type: ZAP,
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		return &ExporterInstance{
+			Trace: zap.NewZapTraceExporter(),
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("zap", zapFactory)
+}
@@ -0,0 +1,108 @@
+package occonfig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"contrib.go.opencensus.io/exporter/zipkin"
+	openzipkin "github.com/openzipkin/zipkin-go"
+	zipkinHTTP "github.com/openzipkin/zipkin-go/reporter/http"
+)
+
+// zipkinReporterOptions reads batchSize, batchInterval, maxBacklog and timeout in the URL query.
+func zipkinReporterOptions(exporter *Exporter) ([]zipkinHTTP.ReporterOption, error) {
+	var options []zipkinHTTP.ReporterOption
+	positiveInt := func(key string, option func(int) zipkinHTTP.ReporterOption) error {
+		rawValue := exporter.Options.Get(key)
+		if rawValue == "" {
+			return nil
+		}
+		value, err := strconv.Atoi(rawValue)
+		if err != nil || value <= 0 {
+			return fmt.Errorf("Invalid Zipkin %s %q. It should be a positive number", key, rawValue)
+		}
+		options = append(options, option(value))
+		return nil
+	}
+	positiveDuration := func(key string, defaultValue time.Duration, option func(time.Duration) zipkinHTTP.ReporterOption) error {
+		rawValue := exporter.Options.Get(key)
+		if rawValue == "" {
+			if defaultValue > 0 {
+				options = append(options, option(defaultValue))
+			}
+			return nil
+		}
+		value, err := time.ParseDuration(rawValue)
+		if err != nil || value <= 0 {
+			return fmt.Errorf("Invalid Zipkin %s %q. It should be a positive duration like 5s", key, rawValue)
+		}
+		options = append(options, option(value))
+		return nil
+	}
+	if err := positiveInt("batchSize", zipkinHTTP.BatchSize); err != nil {
+		return nil, err
+	}
+	if err := positiveInt("maxBacklog", zipkinHTTP.MaxBacklog); err != nil {
+		return nil, err
+	}
+	if err := positiveDuration("batchInterval", 0, zipkinHTTP.BatchInterval); err != nil {
+		return nil, err
+	}
+	// 5 seconds is the default timeout of zipkin-go. It is needed because occonfig passes its own client
+	if err := positiveDuration("timeout", 5*time.Second, zipkinHTTP.Timeout); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+var zipkinFactory = &ExporterFactory{
+	Name: "Zipkin",
+	Mode: Trace,
+	Parse: func(u *url.URL) (*Exporter, error) {
+		host, port := hostPort(u, "localhost", "9411")
+		path := u.Path
+		if path == "" {
+			path = "/api/v2/spans"
+		}
+		return &Exporter{
+			Type: ZIPKIN,
+			Host: fmt.Sprintf("%s://%s:%s%s", httpScheme(u), host, port, path),
+		}, nil
+	},
+	New: func(ctx context.Context, exporter *Exporter, config *Config, logger *log.Logger) (*ExporterInstance, error) {
+		localEndpointURI := config.ServiceUrl
+		reporterURI := httpEndpoint(exporter)
+		serviceName := config.ServiceName
+
+		localEndpoint, err := openzipkin.NewEndpoint(serviceName, localEndpointURI)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create Zipkin localEndpoint with URI %q error: %v", localEndpointURI, err)
+		}
+
+		reporterOptions, err := zipkinReporterOptions(exporter)
+		if err != nil {
+			return nil, err
+		}
+		client, err := newHTTPClient(exporter, config)
+		if err != nil {
+			return nil, err
+		}
+		// Client option should be first because Timeout option modifies the client
+		reporterOptions = append([]zipkinHTTP.ReporterOption{zipkinHTTP.Client(client)}, reporterOptions...)
+		reporter := zipkinHTTP.NewReporter(reporterURI, reporterOptions...)
+		return &ExporterInstance{
+			Trace: zipkin.NewExporter(reporter, localEndpoint),
+			// Close sends the buffered spans and stops the reporter goroutine
+			Finalize: reporter.Close,
+		}, nil
+	},
+}
+
+func init() {
+	RegisterExporterFactory("zipkin", zipkinFactory)
+	RegisterExporterFactory("zipkin+https", zipkinFactory)
+}
@@ -0,0 +1,101 @@
+package occonfig
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZipkinReporterOptions(t *testing.T) {
+	testcases := []struct {
+		Name    string
+		Source  string
+		Options map[string]url.Values
+		Count   int
+		Error   bool
+	}{
+		{
+			Name:   "default (timeout only)",
+			Source: "zipkin://collector",
+			Count:  1,
+		},
+		{
+			Name:   "all options",
+			Source: "zipkin://collector?batchSize=10&batchInterval=2s&maxBacklog=100&timeout=10s",
+			Count:  4,
+		},
+		{
+			Name:    "JSON options",
+			Source:  "zipkin://collector",
+			Options: map[string]url.Values{"zipkin": {"batchSize": {"10"}}},
+			Count:   2,
+		},
+		{
+			Name:   "invalid batch size",
+			Source: "zipkin://collector?batchSize=0",
+			Error:  true,
+		},
+		{
+			Name:   "invalid max backlog",
+			Source: "zipkin://collector?maxBacklog=many",
+			Error:  true,
+		},
+		{
+			Name:   "invalid batch interval",
+			Source: "zipkin://collector?batchInterval=2",
+			Error:  true,
+		},
+		{
+			Name:   "invalid timeout",
+			Source: "zipkin://collector?timeout=-1s",
+			Error:  true,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.Name, func(t *testing.T) {
+			exporter, err := SelectTraceExporter(testcase.Source)
+			assert.Nil(t, err)
+			applyExporterOptions(exporter, &Config{ExporterOptions: testcase.Options})
+			options, err := zipkinReporterOptions(exporter)
+			if testcase.Error {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.Count, len(options))
+		})
+	}
+}
+
+func TestZipkinFinalizeSendsBufferedSpans(t *testing.T) {
+	var lock sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lock.Lock()
+		bodies = append(bodies, string(body))
+		lock.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	// long batch interval keeps the span in the buffer until Finalize
+	exporter, err := SelectTraceExporter(strings.Replace(server.URL, "http://", "zipkin://", 1) + "/api/v2/spans?batchInterval=1h")
+	assert.Nil(t, err)
+	instance, err := zipkinFactory.New(context.Background(), exporter, &Config{ServiceName: "my-service"}, testLogger)
+	assert.Nil(t, err)
+	exportTestSpan(t, instance)
+
+	lock.Lock()
+	defer lock.Unlock()
+	if assert.Equal(t, 1, len(bodies)) {
+		assert.True(t, strings.Contains(bodies[0], "test-span"))
+	}
+}